@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/y0ug/ai-helper/internal/config"
+	"github.com/y0ug/ai-helper/internal/io"
+)
+
+// configPaths returns the config files to merge, lowest precedence first: the global
+// file, the project files from the repository root down to the current directory,
+// then configFile when set
+func configPaths(configFile string) ([]string, error) {
+	paths, err := io.FindConfigFiles(".")
+	if err != nil {
+		return nil, err
+	}
+	if configFile != "" {
+		paths = append(paths, configFile)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no config file found in the current directory, its repository or %s", io.GetConfigDir())
+	}
+	return paths, nil
+}
+
+// runConfig handles the config subcommand and returns the exit code. It needs neither
+// a model nor credentials, so it can run in CI.
+func runConfig(args []string, configFile string) int {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(os.Stderr, "Usage: ai-helper config validate [FILE...]")
+		return ExitUsage
+	}
+
+	paths := args[1:]
+	if len(paths) == 0 {
+		var err error
+		if paths, err = configPaths(configFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return ExitConfig
+		}
+	}
+
+	cfg, err := config.NewLoader().Read(paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return ExitConfig
+	}
+
+	problems := cfg.Problems(true)
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%d problem(s) found\n", len(problems))
+		return ExitConfig
+	}
+
+	fmt.Printf("%d commands OK in %s\n", len(cfg.Commands), strings.Join(cfg.Sources, ", "))
+	return ExitOK
+}
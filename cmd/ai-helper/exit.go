@@ -0,0 +1,13 @@
+package main
+
+// Exit codes of ai-helper, documented in the README
+const (
+	ExitOK       = 0
+	ExitError    = 1 // any other error, e.g. reading input or writing output
+	ExitUsage    = 2 // invalid flags or arguments, as returned by the flag package
+	ExitConfig   = 3 // missing or invalid configuration, or a prompt that fails to render
+	ExitProvider = 4 // model, credentials or API request failure
+	ExitBudget   = 5 // request refused because it would exceed a cost limit, reserved
+	ExitGuard    = 6 // response rejected by error_pattern, a guard or an output step
+	ExitPrecheck = 7 // a pre check of the command failed, no request was sent
+)
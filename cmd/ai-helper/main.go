@@ -1,19 +1,24 @@
 package main
 
 import (
-	"bytes"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
-	"text/template"
 	"time"
 
 	"github.com/y0ug/ai-helper/internal/ai"
+	"github.com/y0ug/ai-helper/internal/ask"
 	"github.com/y0ug/ai-helper/internal/chat"
 	"github.com/y0ug/ai-helper/internal/config"
+	"github.com/y0ug/ai-helper/internal/hook"
 	"github.com/y0ug/ai-helper/internal/io"
+	"github.com/y0ug/ai-helper/internal/markdown"
+	"github.com/y0ug/ai-helper/internal/output"
+	"github.com/y0ug/ai-helper/internal/patch"
+	"github.com/y0ug/ai-helper/internal/secret"
 	"github.com/y0ug/ai-helper/internal/stats"
 	"github.com/y0ug/ai-helper/internal/version"
 )
@@ -46,31 +51,40 @@ func main() {
 	configFile := flag.String("config", "", "Config file path")
 	showStats := flag.Bool("stats", false, "Show usage statistics")
 	showList := flag.Bool("list", false, "List available commands")
-	verbose := flag.Bool("v", false, "Show verbose cost information")
+	verbose := flag.Bool("v", false, "Show verbose cost information, or command origins with --list")
 	genCompletion := flag.String("completion", "", "Generate shell completion script (zsh|bash)")
 	showPrompt := flag.Bool("show-prompt", false, "Show only the generated prompt")
 	attachFiles := flag.String("files", "", "Comma-separated list of files to attach")
 	showVersion := flag.Bool("version", false, "Show version information")
 	interactiveMode := flag.Bool("i", false, "Interactive chat mode")
+	rawOutput := flag.Bool("raw", false, "Print responses as raw Markdown")
+	codeBlock := flag.Int("block", 0, "Output only code block N of the response (negative counts from the end)")
+	codeLang := flag.String("lang", "", "Output only code blocks in this language")
+	applyPatch := flag.Bool("apply", false, "Apply diffs or whole-file code blocks from the response to files")
+	dryRun := flag.Bool("dry-run", false, "With -apply, show and check the changes without writing them")
+	noPost := flag.Bool("no-post", false, "Do not run the post hooks of the command")
+	strict := flag.Bool("strict", false, "Fail when a template reads a missing key")
+	var params paramFlags
+	flag.Var(&params, "param", "Set a parameter of the command as name=value (repeatable)")
 	flag.Parse()
 
-	// Create AI client early as it's needed for multiple features
-	configDir, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to get home directory: %s", err)
-		os.Exit(1)
+	// Handle the config subcommand before anything needing a model or credentials
+	if flag.Arg(0) == "config" {
+		os.Exit(runConfig(flag.Args()[1:], *configFile))
 	}
 
-	configDir = filepath.Join(configDir, ".config", "ai-helper")
+	// Create AI client early as it's needed for multiple features, its API key is only
+	// resolved by the first request
+	configDir := io.GetConfigDir()
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to create config directory: %s", err)
-		os.Exit(1)
+		os.Exit(ExitError)
 	}
 
 	cacheDir := io.GetCacheDir()
 	if err := os.MkdirAll(cacheDir, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "failed to create cache directory: %w", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "failed to create cache directory: %s", err)
+		os.Exit(ExitError)
 	}
 	statsTracker, err := stats.NewTracker(cacheDir)
 
@@ -78,18 +92,24 @@ func main() {
 	infoProviders, err := ai.NewInfoProviders(infoProviderCacheFile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating info providers: %v\n", err)
-		os.Exit(1)
+		os.Exit(ExitProvider)
 	}
 	model, err := GetEnvAIModel(infoProviders)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error getting model: %v\n", err)
-		os.Exit(1)
+		os.Exit(ExitProvider)
 	}
 
-	client, err := ai.NewClient(model, statsTracker)
+	credentials, err := ai.LoadCredentials(filepath.Join(configDir, ai.CredentialsFile))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading credentials: %v\n", err)
+		os.Exit(ExitProvider)
+	}
+
+	client, err := ai.NewClient(model, credentials, statsTracker)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating AI client: %v\n", err)
-		os.Exit(1)
+		os.Exit(ExitProvider)
 	}
 
 	// Handle version display
@@ -97,7 +117,7 @@ func main() {
 		fmt.Printf("ai-helper version %s\n", version.Version)
 		fmt.Printf("Commit: %s\n", version.CommitHash)
 		fmt.Printf("Built: %s\n", version.BuildDate)
-		os.Exit(0)
+		os.Exit(ExitOK)
 	}
 
 	// Handle completion script generation
@@ -105,52 +125,50 @@ func main() {
 		switch *genCompletion {
 		case "zsh":
 			// fmt.Println(generateZshCompletion())
-			os.Exit(0)
+			os.Exit(ExitOK)
 		case "bash":
 			// fmt.Println(generateBashCompletion())
-			os.Exit(0)
+			os.Exit(ExitOK)
 		default:
 			fmt.Fprintf(os.Stderr, "Unsupported shell for completion: %s\n", *genCompletion)
-			os.Exit(1)
+			os.Exit(ExitUsage)
 		}
 	}
 
 	// Load configuration early for list command
-	cfgPath := *configFile
-	if cfgPath == "" {
-		var err error
-		cfgPath, err = io.FindConfigFile(".")
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
-		}
+	cfgPaths, err := configPaths(*configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(ExitConfig)
 	}
 
 	loader := config.NewLoader()
-	cfg, err := loader.Load(cfgPath)
+	cfg, err := loader.LoadFiles(cfgPaths)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		os.Exit(ExitConfig)
 	}
 
 	// Handle list command
 	if *showList {
-		fmt.Println("Available commands:")
-		for name, cmd := range cfg.Commands {
-			if cmd.Description != "" {
-				fmt.Printf("  %-15s %s\n", name, cmd.Description)
-			} else {
-				fmt.Printf("  %s\n", name)
+		if name := flag.Arg(0); name != "" {
+			cmd, ok := cfg.Commands[name]
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Error: Unknown command '%s'\n", name)
+				os.Exit(ExitUsage)
 			}
+			printCommandHelp(name, cmd)
+			os.Exit(ExitOK)
 		}
-		os.Exit(0)
+		listCommands(cfg, *verbose)
+		os.Exit(ExitOK)
 	}
 
 	// Handle stats display
 	if *showStats {
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error creating stats tracker: %v\n", err)
-			os.Exit(1)
+			os.Exit(ExitError)
 		}
 
 		stats := statsTracker.GetStats()
@@ -159,7 +177,7 @@ func main() {
 
 		if len(stats) == 0 {
 			fmt.Println("No statistics available yet.")
-			os.Exit(0)
+			os.Exit(ExitOK)
 		}
 
 		for provider, pStats := range stats {
@@ -185,97 +203,47 @@ func main() {
 				}
 			}
 		}
-		os.Exit(0)
+		os.Exit(ExitOK)
 	}
 
 	// Create an agent for this command
 	agent := ai.NewAgent(generateSessionID(), model, client)
+	agent.SessionRules = cfg.SessionRules()
+	agent.Strict = *strict
 
-	// Handle interactive mode
-	if *interactiveMode {
-		command := ""
-		if len(flag.Args()) > 0 {
-			command = flag.Args()[0]
+	// Files from the --files flag, loaded before the system template is rendered
+	var files []string
+	if *attachFiles != "" {
+		for _, path := range strings.Split(*attachFiles, ",") {
+			files = append(files, strings.TrimSpace(path))
 		}
+	}
 
-		command = strings.TrimSpace(command)
-		var initialPrompt, systemPrompt string
-		if command != "" {
-			// Get command configuration
-			cmd, ok := cfg.Commands[command]
-			if !ok {
-				fmt.Fprintf(os.Stderr, "Error: Unknown command '%s'\n", command)
-				os.Exit(1)
-			}
-
-			// Load prompt and system prompt content
-			promptContent, systemContent, vars, err := config.LoadPromptContent(cmd)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error loading prompt: %v\n", err)
-				os.Exit(1)
-			}
-
-			// Process the prompts with template data
-			templateData := map[string]interface{}{
-				"env":   make(map[string]string),
-				"Files": make(map[string]string),
-			}
-			// Add variables from command config
-			for k, v := range vars {
-				templateData[k] = v
-			}
-
-			// Parse and execute the prompts
-			tmpl, err := template.New("prompt").Parse(promptContent)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error parsing prompt template: %v\n", err)
-				os.Exit(1)
-			}
-
-			var promptBuf bytes.Buffer
-			if err := tmpl.Execute(&promptBuf, templateData); err != nil {
-				fmt.Fprintf(os.Stderr, "Error executing prompt template: %v\n", err)
-				os.Exit(1)
-			}
-			initialPrompt = promptBuf.String()
-
-			if systemContent != "" {
-				systemTmpl, err := template.New("system").Parse(systemContent)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Error parsing system template: %v\n", err)
-					os.Exit(1)
-				}
-
-				var systemBuf bytes.Buffer
-				if err := systemTmpl.Execute(&systemBuf, templateData); err != nil {
-					fmt.Fprintf(os.Stderr, "Error executing system template: %v\n", err)
-					os.Exit(1)
-				}
-				systemPrompt = systemBuf.String()
+	// Handle interactive mode
+	if *interactiveMode {
+		chatSession := chat.NewChat(agent, cfg, infoProviders, credentials, statsTracker)
+		chatSession.Render = !*rawOutput && io.RenderEnabled()
+
+		// Seed the conversation with a command rendered like in one-shot mode
+		if args := flag.Args(); len(args) > 0 {
+			if err := chatSession.ApplyCommand(args[0], args[1:], params, files); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(ExitError)
 			}
 		}
 
-		chatSession := chat.NewChat(agent)
-
-		if systemPrompt != "" {
-			agent.AddMessage("system", initialPrompt)
-		}
-		if initialPrompt != "" {
-			agent.AddMessage("user", initialPrompt)
-		}
-
 		if err := chatSession.Start(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error in chat mode: %v\n", err)
-			os.Exit(1)
+			os.Exit(ExitError)
 		}
-		os.Exit(0)
+		os.Exit(ExitOK)
 	}
 
 	// Get the command and remaining args
 	args := flag.Args()
 	if len(args) < 1 {
 		fmt.Fprintln(os.Stderr, "Error: Command required")
-		os.Exit(1)
+		os.Exit(ExitUsage)
 	}
 	command := args[0]
 	inputArgs := args[1:]
@@ -283,80 +251,120 @@ func main() {
 	// Create config loader and load config
 	if err := cfg.ValidateConfig(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		os.Exit(ExitConfig)
 	}
 
 	// Get command configuration
 	cmd, ok := cfg.Commands[command]
 	if !ok {
 		fmt.Fprintf(os.Stderr, "Error: Unknown command '%s'\n", command)
-		os.Exit(1)
+		os.Exit(ExitUsage)
 	}
 
-	// Prepare input configuration
-	var inputTypes []string
-	var fallbackCmd string
+	// Resolve the command's parameters before anything is read or sent
+	paramValues, inputArgs, err := cmd.ParseParams(params, inputArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\nSee: ai-helper --list %s\n", err, command)
+		os.Exit(ExitUsage)
+	}
+	for name, value := range paramValues {
+		agent.TemplateData.Vars[name] = value
+	}
 
-	// Look for input variable configuration
-	for _, v := range cmd.Variables {
-		if v.Name == "Input" && v.Type != "" {
-			// Split type string in case it contains multiple types (e.g. "stdin|arg")
-			inputTypes = strings.Split(v.Type, "|")
-			fallbackCmd = v.Exec
-			break
+	// Use the command's model instead of AI_MODEL
+	if cmd.Model != "" {
+		cmdModel, err := ai.ParseModel(cmd.Model, infoProviders)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting model of command '%s': %v\n", command, err)
+			os.Exit(ExitConfig)
+		}
+		cmdClient, err := ai.NewClient(cmdModel, credentials, statsTracker)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating AI client: %v\n", err)
+			os.Exit(ExitProvider)
 		}
+		agent.SetModel(cmdModel, cmdClient)
 	}
 
 	// Read input only if command requires it
 	var input string
-	if cmd.Input {
+	if cmd.ReadsInput() {
 		var err error
-		input, err = io.ReadInput(inputArgs, inputTypes, fallbackCmd)
+		input, err = io.ReadInput(inputArgs, cmd.InputSpec())
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
-			os.Exit(1)
+			os.Exit(ExitError)
 		}
 		agent.TemplateData.Input = input
 	}
 
-	// Load command configuration into agent
-	if err := agent.LoadCommand(&cmd); err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading command: %v\n", err)
-		os.Exit(1)
+	// Stop before any request when the command's pre checks fail
+	if err := hook.RunPre(cmd.Pre, hook.Data{Input: input}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(ExitPrecheck)
 	}
 
-	// Add files from command line flag
-	if *attachFiles != "" {
-		additionalFiles := strings.Split(*attachFiles, ",")
-		for _, filepath := range additionalFiles {
-			filepath = strings.TrimSpace(filepath)
-			if err := agent.TemplateData.LoadFiles([]string{filepath}); err != nil {
-				fmt.Fprintf(os.Stderr, "Error loading additional file %s: %v\n", filepath, err)
-				os.Exit(1)
-			}
+	// Ask for the variables not answered on the command line
+	answers, err := ask.Variables(cmd.Variables, paramValues)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(ExitError)
+	}
+	for name, value := range answers {
+		agent.TemplateData.Vars[name] = value
+	}
+
+	outputs, err := config.ExecVariables(cmd.Variables)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running variable commands: %v\n", err)
+		os.Exit(ExitError)
+	}
+	for name, value := range outputs {
+		agent.TemplateData.Vars[name] = value
+	}
+
+	for _, path := range files {
+		if err := agent.TemplateData.LoadFiles([]string{path}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading additional file %s: %v\n", path, err)
+			os.Exit(ExitError)
 		}
 	}
 
+	// Load command configuration into agent
+	if err := agent.LoadCommand(&cmd); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading command: %v\n", err)
+		os.Exit(ExitConfig)
+	}
+
 	// Apply the command with input
 	if err := agent.ApplyCommand(input); err != nil {
 		fmt.Fprintf(os.Stderr, "Error applying command: %v\n", err)
-		os.Exit(1)
+		os.Exit(ExitConfig)
+	}
+
+	// Keep secrets found in the prompt from being sent, as the command's policy says
+	policy, _ := secret.ParsePolicy(cmd.Secrets)
+	findings, err := agent.ScanSecrets(policy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(ExitPrecheck)
 	}
+	reportSecrets(findings, policy)
 
-	// If show-prompt flag is set, print the last user message and exit
+	// If show-prompt flag is set, print the messages and exit without sending them
 	if *showPrompt {
 		msgs := agent.GetMessages()
 		for _, v := range msgs {
 			fmt.Printf("%s: %s\n", v.Role, v.Content)
 		}
-		os.Exit(1)
+		os.Exit(ExitOK)
 	}
 
 	// Generate response using the agent
 	resp, err := agent.SendRequest()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error generating response: %v\n", err)
-		os.Exit(1)
+		os.Exit(ExitProvider)
 	}
 
 	// Print token usage and cost to stderr
@@ -384,26 +392,268 @@ func main() {
 	if *outputFile != "" {
 		if err := io.EnsureDirectory(*outputFile); err != nil {
 			fmt.Fprintf(os.Stderr, "Error creating output directory: %v\n", err)
-			os.Exit(1)
+			os.Exit(ExitError)
+		}
+	}
+
+	// Reject error sentinels, then post-process the response and check the result
+	result, err := checkResponse(resp.Content, cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		agent.Save()
+		os.Exit(ExitGuard)
+	}
+
+	// Keep only the requested code blocks, printed as plain code
+	render := !*rawOutput && io.RenderEnabled()
+	if *codeBlock != 0 || *codeLang != "" {
+		blocks, err := markdown.SelectCodeBlocks(
+			markdown.ExtractCodeBlocks(result),
+			*codeLang,
+			*codeBlock,
+		)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error extracting code: %v\n", err)
+			os.Exit(ExitError)
 		}
+		result = markdown.JoinCodeBlocks(blocks)
+		render = false
 	}
 
 	// Write output
-	if err := io.WriteOutput(resp.Content, *outputFile); err != nil {
+	if err := io.WriteOutput(result, *outputFile, render); err != nil {
 		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
-		os.Exit(1)
+		os.Exit(ExitError)
+	}
+
+	// Act on the output with the command's post hooks
+	if len(cmd.Post) > 0 && !*noPost {
+		if err := hook.RunPost(cmd.Post, hook.Data{Input: input, Response: result}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error running post hook: %v\n", err)
+			agent.Save()
+			os.Exit(ExitError)
+		}
+	}
+
+	if *applyPatch {
+		if err := applyChanges(resp.Content, *dryRun); err != nil {
+			fmt.Fprintf(os.Stderr, "Error applying changes: %v\n", err)
+			agent.Save()
+			os.Exit(ExitError)
+		}
 	}
 
 	agent.Save()
 }
 
+// listCommands prints the available commands, with the file defining each one and the
+// definitions it overrides when verbose is set
+func listCommands(cfg *config.Config, verbose bool) {
+	names := make([]string, 0, len(cfg.Commands))
+	for name := range cfg.Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if verbose {
+		fmt.Println("Config files, lowest precedence first:")
+		for _, path := range cfg.Sources {
+			fmt.Printf("  %s\n", path)
+		}
+		fmt.Println()
+	}
+
+	fmt.Println("Available commands:")
+	for _, name := range names {
+		cmd := cfg.Commands[name]
+		description := cmd.Description
+		if len(cmd.Params) > 0 {
+			usages := make([]string, len(cmd.Params))
+			for i, p := range cmd.Params {
+				usages[i] = p.Name
+			}
+			description = strings.TrimSpace(fmt.Sprintf("%s [params: %s]", description, strings.Join(usages, ", ")))
+		}
+		if description != "" {
+			fmt.Printf("  %-15s %s\n", name, description)
+		} else {
+			fmt.Printf("  %s\n", name)
+		}
+		if verbose {
+			fmt.Printf("  %-15s from %s\n", "", cmd.Source)
+			if cmd.Extends != "" {
+				fmt.Printf("  %-15s extends %s\n", "", cmd.Extends)
+			}
+			for _, path := range cmd.Overrides {
+				fmt.Printf("  %-15s overrides %s\n", "", path)
+			}
+		}
+	}
+}
+
+// printCommandHelp prints the usage of a command and its parameters
+func printCommandHelp(name string, cmd config.Command) {
+	usage := "ai-helper " + name
+	if len(cmd.Params) > 0 || len(cmd.Variables) > 0 {
+		usage += " [--param name=value]... [name=value]..."
+	}
+	if cmd.ReadsInput() {
+		usage += " [INPUT]"
+	}
+
+	if cmd.Description != "" {
+		fmt.Printf("%s - %s\n\n", name, cmd.Description)
+	}
+	fmt.Printf("Usage: %s\n", usage)
+
+	if cmd.ReadsInput() {
+		spec := cmd.InputSpec()
+		var sources []string
+		for _, t := range spec.Types {
+			switch t {
+			case "exec":
+				t += ": " + spec.Exec
+			case "glob":
+				t += ": " + spec.Glob
+			}
+			sources = append(sources, t)
+		}
+		if len(sources) > 0 {
+			fmt.Printf("\nInput: %s\n", strings.Join(sources, ", "))
+		}
+	}
+
+	var questions []config.Variable
+	for _, v := range cmd.Variables {
+		if v.Type == "ask" {
+			questions = append(questions, v)
+		}
+	}
+	if len(questions) > 0 {
+		fmt.Println("\nAsked when not given as name=value:")
+		for _, v := range questions {
+			kind := v.Ask
+			if kind == "" {
+				kind = "text"
+			}
+			fmt.Printf("  %-25s %s\n", fmt.Sprintf("%s=<%s>", v.Name, kind), v.Question)
+		}
+	}
+
+	if len(cmd.Params) == 0 {
+		return
+	}
+	fmt.Println("\nParameters:")
+	for _, p := range cmd.Params {
+		var notes []string
+		if p.Required {
+			notes = append(notes, "required")
+		}
+		if p.Default != nil {
+			notes = append(notes, fmt.Sprintf("default: %v", p.Default))
+		}
+		help := p.Description
+		if len(notes) > 0 {
+			help = strings.TrimSpace(fmt.Sprintf("%s (%s)", help, strings.Join(notes, ", ")))
+		}
+		fmt.Printf("  %-25s %s\n", p.Usage(), help)
+	}
+}
+
+// paramFlags collects the repeated --param flags
+type paramFlags []string
+
+func (p *paramFlags) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *paramFlags) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+// reportSecrets warns about the secrets found in the prompt
+func reportSecrets(findings []secret.Finding, policy secret.Policy) {
+	if len(findings) == 0 {
+		return
+	}
+	action := "sent as is"
+	if policy == secret.PolicyRedact {
+		action = "redacted"
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %d possible secret(s) %s:\n", len(findings), action)
+	for _, f := range findings {
+		fmt.Fprintf(os.Stderr, "  %s\n", f)
+	}
+}
+
+// checkResponse applies the error pattern, output steps and guards of cmd to a response
+func checkResponse(content string, cmd config.Command) (string, error) {
+	if err := output.CheckError(content, cmd.ErrorPattern); err != nil {
+		return "", err
+	}
+	result, err := output.Process(content, cmd.Output)
+	if err != nil {
+		return "", fmt.Errorf("failed to process output: %w", err)
+	}
+	if err := output.CheckGuards(result, cmd.Guards); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// applyChanges previews the file changes proposed in content and writes them after
+// confirmation, backing up the previous state first
+func applyChanges(content string, dryRun bool) error {
+	changes, err := patch.Parse(content)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return fmt.Errorf("no diff or file block found in the response")
+	}
+
+	results, err := patch.Prepare(changes)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr)
+	patch.Preview(os.Stderr, results, io.IsTerminal(os.Stderr) && os.Getenv("NO_COLOR") == "")
+	if dryRun {
+		fmt.Fprintf(os.Stderr, "Dry run: %d file(s) would be changed\n", len(results))
+		return nil
+	}
+
+	ok, err := io.Confirm(fmt.Sprintf("Apply changes to %d file(s)?", len(results)))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Changes not applied.")
+		return nil
+	}
+
+	msg, err := patch.Backup(results, filepath.Join(io.GetCacheDir(), "backups"))
+	if err != nil {
+		return fmt.Errorf("backup failed, nothing applied: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, msg)
+
+	if err := patch.Write(results); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Applied changes to %d file(s)\n", len(results))
+	return nil
+}
+
 func generateBashCompletion() string {
 	return `_ai_helper() {
     local cur prev opts
     COMPREPLY=()
     cur="${COMP_WORDS[COMP_CWORD]}"
     prev="${COMP_WORDS[COMP_CWORD-1]}"
-    opts="-output -config -stats -list -v -completion -show-prompt -files -version -i"
+    opts="-output -config -stats -list -v -completion -show-prompt -files -version -i -raw -block -lang -apply -dry-run -no-post -param -strict"
 
     if [[ ${cur} == -* ]] ; then
         COMPREPLY=( $(compgen -W "${opts}" -- ${cur}) )
@@ -3,6 +3,13 @@ module github.com/y0ug/ai-helper
 go 1.23.3
 
 require (
+	github.com/alecthomas/chroma/v2 v2.20.0
 	go.uber.org/mock v0.5.0
+	golang.org/x/term v0.34.0
 	gopkg.in/yaml.v3 v3.0.1
 )
+
+require (
+	github.com/dlclark/regexp2 v1.11.5 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+)
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/y0ug/ai-helper/internal/config"
 	"github.com/y0ug/ai-helper/internal/prompt"
+	"github.com/y0ug/ai-helper/internal/secret"
 )
 
 type AIConversation interface {
@@ -28,6 +30,7 @@ type AgentState struct {
 	ID                string               `json:"id"`
 	ModelName         string               `json:"model"`
 	Messages          []Message            `json:"messages"`
+	Parameters        Parameters           `json:"parameters"`
 	Command           *config.Command      `json:"command,omitempty"`
 	TemplateData      *prompt.TemplateData `json:"-"` // Skip normal JSON marshaling
 	CreatedAt         time.Time            `json:"created_at"`
@@ -35,6 +38,8 @@ type AgentState struct {
 	TotalInputTokens  int                  `json:"total_input_tokens"`
 	TotalOutputTokens int                  `json:"total_output_tokens"`
 	TotalCost         float64              `json:"total_cost"`
+
+	redact []secret.Rule // extra patterns redacted when saving
 }
 
 // Agent represents an AI conversation agent that maintains state and history
@@ -43,6 +48,7 @@ type Agent struct {
 	Model             *Model // The AI model being used
 	Client            AIClient
 	Messages          []Message            // Conversation history
+	Parameters        Parameters           // Generation settings sent with each request
 	Command           *config.Command      // Current active command
 	TemplateData      *prompt.TemplateData // Data for template processing
 	CreatedAt         time.Time            // When the agent was created
@@ -50,6 +56,11 @@ type Agent struct {
 	TotalInputTokens  int                  // Total tokens used in inputs
 	TotalOutputTokens int                  // Total tokens used in outputs
 	TotalCost         float64              // Total cost accumulated
+	SessionRules      []secret.Rule        // Extra patterns redacted from saved sessions
+	Strict            bool                 // Fail on missing template keys, as if every command set strict
+
+	redactor *secret.Redactor // placeholders given to secrets, consistent across messages
+	scanned  map[string]bool  // message contents already scanned for secrets
 }
 
 // MarshalJSON implements custom JSON marshaling for AgentState
@@ -60,15 +71,33 @@ func (s AgentState) MarshalJSON() ([]byte, error) {
 	sanitizedData := *s.TemplateData
 	sanitizedData.Env = make(map[string]string)
 
-	// Copy environment vars, censoring those ending with _API_KEY
+	// Copy environment vars, masking credentials found by name or by value
 	for k, v := range s.TemplateData.Env {
-		if strings.HasSuffix(strings.ToUpper(k), "_API_KEY") {
+		if secret.IsSensitiveName(k) || len(secret.ScanWith(v, s.redact)) > 0 {
 			sanitizedData.Env[k] = "********"
 		} else {
 			sanitizedData.Env[k] = v
 		}
 	}
 
+	// Input, files, variables and messages may hold secrets sent with the warn policy
+	// or matching the session rules only
+	sanitizedData.Input = secret.RedactString(s.TemplateData.Input, s.redact)
+	sanitizedData.Files = make(map[string]string, len(s.TemplateData.Files))
+	for k, v := range s.TemplateData.Files {
+		sanitizedData.Files[k] = secret.RedactString(v, s.redact)
+	}
+	sanitizedData.Vars = make(map[string]interface{}, len(s.TemplateData.Vars))
+	for k, v := range s.TemplateData.Vars {
+		sanitizedData.Vars[k] = secret.RedactString(fmt.Sprint(v), s.redact)
+	}
+	messages := make([]Message, len(s.Messages))
+	for i, msg := range s.Messages {
+		msg.Content = secret.RedactString(msg.Content, s.redact)
+		messages[i] = msg
+	}
+	s.Messages = messages
+
 	// Use the alias type with our sanitized data
 	return json.Marshal(&struct {
 		Alias
@@ -83,8 +112,20 @@ func (s AgentState) MarshalJSON() ([]byte, error) {
 func (a *Agent) LoadCommand(cmd *config.Command) error {
 	a.Command = cmd
 
+	// Apply the command's generation parameters
+	names := make([]string, 0, len(cmd.Parameters))
+	for name := range cmd.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := a.Parameters.Set(name, fmt.Sprint(cmd.Parameters[name])); err != nil {
+			return fmt.Errorf("invalid command parameter: %w", err)
+		}
+	}
+
 	// Load environment variables
-	a.TemplateData.LoadEnvironment()
+	a.TemplateData.LoadEnvironment(cmd.EnvNames())
 
 	// Load any required files
 	if len(cmd.Files) > 0 {
@@ -95,7 +136,7 @@ func (a *Agent) LoadCommand(cmd *config.Command) error {
 
 	// Process system message template if present
 	if cmd.System != "" {
-		systemMsg, err := prompt.Execute(cmd.System, a.TemplateData)
+		systemMsg, err := a.Render(cmd.System)
 		if err != nil {
 			return fmt.Errorf("failed to process system template: %w", err)
 		}
@@ -115,7 +156,7 @@ func (a *Agent) ApplyCommand(input string) error {
 	a.TemplateData.Input = input
 
 	// Process the prompt template
-	processedPrompt, err := prompt.Execute(a.Command.Prompt, a.TemplateData)
+	processedPrompt, err := a.Render(a.Command.Prompt)
 	if err != nil {
 		return fmt.Errorf("failed to process prompt template: %w", err)
 	}
@@ -124,6 +165,16 @@ func (a *Agent) ApplyCommand(input string) error {
 	return nil
 }
 
+// Render renders a template of the loaded command with the agent's template data
+func (a *Agent) Render(content string) (string, error) {
+	opts := prompt.Options{Strict: a.Strict}
+	if a.Command != nil {
+		opts.Partials = a.Command.Partials
+		opts.Strict = opts.Strict || a.Command.IsStrict()
+	}
+	return prompt.Render(content, a.TemplateData, opts)
+}
+
 // NewAgent creates a new Agent instance
 func NewAgent(id string, model *Model, client *Client) *Agent {
 	now := time.Now()
@@ -146,7 +197,7 @@ func (a *Agent) Save() error {
 	}
 
 	agentDir := filepath.Join(cacheDir, "ai-helper", "agents")
-	if err := os.MkdirAll(agentDir, 0755); err != nil {
+	if err := os.MkdirAll(agentDir, 0700); err != nil {
 		return fmt.Errorf("failed to create agent directory: %w", err)
 	}
 
@@ -154,6 +205,7 @@ func (a *Agent) Save() error {
 		ID:                a.ID,
 		ModelName:         a.Model.Name,
 		Messages:          a.Messages,
+		Parameters:        a.Parameters,
 		Command:           a.Command,
 		TemplateData:      a.TemplateData,
 		CreatedAt:         a.CreatedAt,
@@ -161,6 +213,7 @@ func (a *Agent) Save() error {
 		TotalInputTokens:  a.TotalInputTokens,
 		TotalOutputTokens: a.TotalOutputTokens,
 		TotalCost:         a.TotalCost,
+		redact:            a.SessionRules,
 	}
 
 	data, err := json.MarshalIndent(state, "", "  ")
@@ -169,9 +222,13 @@ func (a *Agent) Save() error {
 	}
 
 	filename := filepath.Join(agentDir, fmt.Sprintf("%s.json", a.ID))
-	if err := os.WriteFile(filename, data, 0644); err != nil {
+	if err := os.WriteFile(filename, data, 0600); err != nil {
 		return fmt.Errorf("failed to write agent state: %w", err)
 	}
+	// Sessions written by older versions may be readable by others
+	if err := os.Chmod(filename, 0600); err != nil {
+		return fmt.Errorf("failed to set agent state permissions: %w", err)
+	}
 
 	return nil
 }
@@ -198,13 +255,16 @@ func LoadAgent(id string, model *Model) (*Agent, error) {
 		ID:                state.ID,
 		Model:             model,
 		Messages:          state.Messages,
+		Parameters:        state.Parameters,
 		Command:           state.Command,
-		TemplateData:      state.TemplateData,
 		CreatedAt:         state.CreatedAt,
 		UpdatedAt:         state.UpdatedAt,
 		TotalInputTokens:  state.TotalInputTokens,
 		TotalOutputTokens: state.TotalOutputTokens,
 		TotalCost:         state.TotalCost,
+		// Saved template data is redacted and only kept for reference, the next command
+		// loads its own
+		TemplateData: prompt.NewTemplateData(""),
 	}
 
 	return agent, nil
@@ -220,17 +280,131 @@ func (a *Agent) UpdateCosts(response *Response) {
 }
 
 func (a *Agent) SendRequest() (Response, error) {
-	resp, err := a.Client.GenerateWithMessages(a.GetMessages(), "agent_name")
+	return a.SendRequestWith(a.Client, a.Model)
+}
+
+// SendRequestWith sends the conversation using the given client and model instead of
+// the agent's own, which allows regenerating a single answer with another model
+func (a *Agent) SendRequestWith(client AIClient, model *Model) (Response, error) {
+	resp, err := client.GenerateWithMessages(a.GetMessages(), "agent_name", a.Parameters)
 	if err != nil {
 		return Response{}, err
 	}
 
-	a.AddMessage("assistant", resp.Content)
+	a.Messages = append(a.Messages, Message{
+		Role:    "assistant",
+		Content: resp.Content,
+		Model:   model.String(),
+	})
 
 	a.UpdateCosts(&resp)
 	return resp, nil
 }
 
+// ScanSecrets scans the system and user messages not scanned yet for secrets and applies
+// the policy: redact replaces them with placeholders in the conversation, block returns
+// a *secret.BlockedError and leaves the messages as they are, warn changes nothing. The
+// findings are returned so they can be reported.
+func (a *Agent) ScanSecrets(policy secret.Policy) ([]secret.Finding, error) {
+	if policy == secret.PolicyOff {
+		return nil, nil
+	}
+	if a.scanned == nil {
+		a.scanned = make(map[string]bool)
+	}
+	if a.redactor == nil {
+		a.redactor = secret.NewRedactor()
+	}
+
+	var all []secret.Finding
+	var pending []int
+	for i, msg := range a.Messages {
+		if msg.Role == "assistant" || a.scanned[msg.Content] {
+			continue
+		}
+		findings := secret.Scan(msg.Content)
+		if len(findings) == 0 {
+			a.scanned[msg.Content] = true
+			continue
+		}
+		all = append(all, findings...)
+		pending = append(pending, i)
+	}
+
+	if len(all) > 0 && policy == secret.PolicyBlock {
+		return all, &secret.BlockedError{Findings: all}
+	}
+	for _, i := range pending {
+		if policy == secret.PolicyRedact {
+			a.Messages[i].Content = a.redactor.Redact(a.Messages[i].Content, secret.Scan(a.Messages[i].Content))
+		}
+		a.scanned[a.Messages[i].Content] = true
+	}
+	return all, nil
+}
+
+// RedactText replaces the secrets found in text with the placeholders of the
+// conversation, the ones ScanSecrets gives them in messages, and returns the findings
+func (a *Agent) RedactText(text string) (string, []secret.Finding) {
+	findings := secret.Scan(text)
+	if len(findings) == 0 {
+		return text, nil
+	}
+	if a.redactor == nil {
+		a.redactor = secret.NewRedactor()
+	}
+	return a.redactor.Redact(text, findings), findings
+}
+
+// SetModel switches the agent to another model, keeping the conversation history
+func (a *Agent) SetModel(model *Model, client AIClient) {
+	a.Model = model
+	a.Client = client
+}
+
+// lastIndex returns the index of the last message with the given role, or -1
+func (a *Agent) lastIndex(role string) int {
+	for i := len(a.Messages) - 1; i >= 0; i-- {
+		if a.Messages[i].Role == role {
+			return i
+		}
+	}
+	return -1
+}
+
+// LastUserMessage returns the content of the most recent user message
+func (a *Agent) LastUserMessage() (string, error) {
+	idx := a.lastIndex("user")
+	if idx < 0 {
+		return "", fmt.Errorf("no user message in conversation")
+	}
+	return a.Messages[idx].Content, nil
+}
+
+// DropLastResponse removes the trailing assistant message so the last user message
+// can be sent again. It is a no-op when the conversation already ends with a user message.
+// Token and cost totals are kept since that usage was already billed.
+func (a *Agent) DropLastResponse() error {
+	if len(a.Messages) == 0 || a.lastIndex("user") < 0 {
+		return fmt.Errorf("no user message to answer")
+	}
+	if a.Messages[len(a.Messages)-1].Role == "assistant" {
+		a.Messages = a.Messages[:len(a.Messages)-1]
+	}
+	return nil
+}
+
+// Undo removes the last user message and everything after it.
+// Token and cost totals are kept since that usage was already billed.
+func (a *Agent) Undo() error {
+	idx := a.lastIndex("user")
+	if idx < 0 {
+		return fmt.Errorf("nothing to undo")
+	}
+	a.Messages = a.Messages[:idx]
+	return nil
+}
+
 // ListAgents returns a list of all saved agent IDs
 func ListAgents() ([]string, error) {
 	cacheDir, err := os.UserCacheDir()
@@ -239,7 +413,7 @@ func ListAgents() ([]string, error) {
 	}
 
 	agentDir := filepath.Join(cacheDir, "ai-helper", "agents")
-	if err := os.MkdirAll(agentDir, 0755); err != nil {
+	if err := os.MkdirAll(agentDir, 0700); err != nil {
 		return nil, fmt.Errorf("failed to create agent directory: %w", err)
 	}
 
@@ -23,10 +23,12 @@ func NewAnthropicProvider(
 
 // AnthropicRequest defines the request structure specific to Anthropic.
 type AnthropicRequest struct {
-	Model     string    `json:"model"`
-	System    string    `json:"system,omitempty"`
-	MaxTokens int       `json:"max_tokens"`
-	Messages  []Message `json:"messages"`
+	Model       string    `json:"model"`
+	System      string    `json:"system,omitempty"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
+	Messages    []Message `json:"messages"`
 }
 
 // AnthropicResponse defines the response structure specific to Anthropic.
@@ -42,7 +44,10 @@ type AnthropicResponse struct {
 }
 
 // GenerateResponse sends a request to Anthropic's API and parses the response.
-func (p *AnthropicProvider) GenerateResponse(messages []Message) (Response, error) {
+func (p *AnthropicProvider) GenerateResponse(
+	messages []Message,
+	params Parameters,
+) (Response, error) {
 	var systemPrompt string
 	var userMessages []Message
 
@@ -55,10 +60,12 @@ func (p *AnthropicProvider) GenerateResponse(messages []Message) (Response, erro
 	}
 
 	reqPayload := AnthropicRequest{
-		Model:     p.model.Name,
-		System:    systemPrompt,
-		MaxTokens: 1024,
-		Messages:  userMessages,
+		Model:       p.model.Name,
+		System:      systemPrompt,
+		MaxTokens:   params.GetMaxTokens(),
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+		Messages:    toAPIMessages(userMessages),
 	}
 
 	var apiResp AnthropicResponse
@@ -3,6 +3,7 @@ package ai
 import (
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/y0ug/ai-helper/internal/stats"
 )
@@ -17,72 +18,72 @@ const (
 )
 
 type AIClient interface {
-	GenerateWithMessages(messages []Message, command string) (Response, error)
+	GenerateWithMessages(messages []Message, command string, params Parameters) (Response, error)
 }
 
 var _ AIClient = (*Client)(nil) // Optional: ensures `Client` implements `AIClient`
 
 // Client handles AI model interactions
 type Client struct {
-	provider Provider
-	model    *Model
-	stats    *stats.Tracker
+	model *Model
+	creds *Credentials
+	stats *stats.Tracker
+
+	mu       sync.Mutex
+	provider Provider // created with the API key on the first request
 }
 
-// NewClient creates a new AI client using environment variables
+// NewClient creates a new AI client, getting the API key from creds, or from the
+// environment when creds is nil. The key is only resolved on the first request, so a
+// client that sends none never runs a key_command.
 func NewClient(
 	model *Model,
+	creds *Credentials,
 	statsTracker *stats.Tracker,
 ) (*Client, error) {
-	var apiKey string
-	switch model.Provider {
-	case "anthropic":
-		apiKey = os.Getenv(EnvAnthropicAPIKey)
-		if apiKey == "" {
-			return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable not set")
-		}
-	case "openai":
-		apiKey = os.Getenv(EnvOpenAIAPIKey)
-		if apiKey == "" {
-			return nil, fmt.Errorf("OPENAI_API_KEY environment variable not set")
-		}
-	case "openrouter":
-		apiKey = os.Getenv(EnvOpenRouterAPIKey)
-		if apiKey == "" {
-			return nil, fmt.Errorf("OPENROUTER_API_KEY environment variable not set")
-		}
-	case "gemini":
-		apiKey = os.Getenv(EnvGeminiAPIKey)
-		if apiKey == "" {
-			return nil, fmt.Errorf("GEMINI_API_KEY environment variable not set")
-		}
-	case "deepseek":
-		apiKey = os.Getenv(EnvDeepSeekAPIKey)
-		if apiKey == "" {
-			return nil, fmt.Errorf("DEEPSEEK_API_KEY environment variable not set")
-		}
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", model.Provider)
+	if _, ok := providerKeyEnv[model.Provider]; !ok {
+		return nil, fmt.Errorf("failed to create provider: unsupported provider: %s", model.Provider)
 	}
 
-	provider, err := NewProvider(model, apiKey, nil)
+	return &Client{
+		model: model,
+		creds: creds,
+		stats: statsTracker,
+	}, nil
+}
+
+// getProvider returns the provider of the client, resolving the API key the first time
+func (c *Client) getProvider() (Provider, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.provider != nil {
+		return c.provider, nil
+	}
+
+	apiKey, err := c.creds.APIKey(c.model.Provider)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := NewProvider(c.model, apiKey, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create provider: %w", err)
 	}
-
-	return &Client{
-		provider: provider,
-		model:    model,
-		stats:    statsTracker,
-	}, nil
+	c.provider = provider
+	return provider, nil
 }
 
 // GenerateWithMessages sends a conversation history to the AI model and returns the response
 func (c *Client) GenerateWithMessages(
 	messages []Message,
 	command string,
+	params Parameters,
 ) (Response, error) {
-	resp, err := c.provider.GenerateResponse(messages)
+	provider, err := c.getProvider()
+	if err != nil {
+		return Response{}, err
+	}
+
+	resp, err := provider.GenerateResponse(messages, params)
 	if err != nil {
 		return Response{}, err
 	}
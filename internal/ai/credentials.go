@@ -0,0 +1,139 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CredentialsFile is the name of the file holding provider keys in the config directory
+const CredentialsFile = "credentials.yaml"
+
+// providerKeyEnv maps providers to the environment variable holding their API key
+var providerKeyEnv = map[string]string{
+	"anthropic":  EnvAnthropicAPIKey,
+	"openai":     EnvOpenAIAPIKey,
+	"openrouter": EnvOpenRouterAPIKey,
+	"gemini":     EnvGeminiAPIKey,
+	"deepseek":   EnvDeepSeekAPIKey,
+}
+
+// Credential tells where to find the API key of a provider
+type Credential struct {
+	Key        string `yaml:"key,omitempty"`         // the key itself
+	KeyCommand string `yaml:"key_command,omitempty"` // shell command printing the key
+}
+
+// Credentials resolves provider API keys from, in order: the key_command or key of the
+// credentials file, then the provider's environment variable. Keys are resolved once
+// per process and only kept in memory.
+type Credentials struct {
+	path    string
+	entries map[string]Credential
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+// LoadCredentials reads the credentials file at path. A missing file is not an error,
+// keys then come from the environment. The file must not be accessible by other users.
+func LoadCredentials(path string) (*Credentials, error) {
+	c := &Credentials{path: path, entries: make(map[string]Credential), cache: make(map[string]string)}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat credentials file: %w", err)
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return nil, fmt.Errorf("credentials file %s is accessible by other users (mode %o), run: chmod 600 %s", path, perm, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &c.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file %s: %w", path, err)
+	}
+	for provider, entry := range c.entries {
+		if _, ok := providerKeyEnv[provider]; !ok {
+			return nil, fmt.Errorf("unknown provider %q in credentials file", provider)
+		}
+		if entry.Key != "" && entry.KeyCommand != "" {
+			return nil, fmt.Errorf("credentials for %s set both key and key_command", provider)
+		}
+	}
+	return c, nil
+}
+
+// APIKey returns the API key of a provider. Errors never include the key.
+func (c *Credentials) APIKey(provider string) (string, error) {
+	envName, ok := providerKeyEnv[provider]
+	if !ok {
+		return "", fmt.Errorf("unsupported provider: %s", provider)
+	}
+	if c == nil {
+		return envKey(envName)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if key, ok := c.cache[provider]; ok {
+		return key, nil
+	}
+
+	entry := c.entries[provider]
+	var key string
+	var err error
+	switch {
+	case entry.KeyCommand != "":
+		key, err = commandKey(provider, entry.KeyCommand)
+	case entry.Key != "":
+		key = strings.TrimSpace(entry.Key)
+	default:
+		key, err = envKey(envName)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	c.cache[provider] = key
+	return key, nil
+}
+
+func envKey(envName string) (string, error) {
+	key := os.Getenv(envName)
+	if key == "" {
+		return "", fmt.Errorf("%s environment variable not set and no credentials configured", envName)
+	}
+	return key, nil
+}
+
+// commandKey runs a key_command and returns the first line of its output. Its stdin and
+// stderr are the terminal, e.g. for a password manager prompt, never the piped input of
+// the command being run.
+func commandKey(provider, command string) (string, error) {
+	cmd := exec.Command("sh", "-c", command)
+	if tty, err := os.Open("/dev/tty"); err == nil {
+		defer tty.Close()
+		cmd.Stdin = tty
+	}
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("key_command for %s failed: %w", provider, err)
+	}
+	key, _, _ := strings.Cut(string(out), "\n")
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", fmt.Errorf("key_command for %s printed nothing", provider)
+	}
+	return key, nil
+}
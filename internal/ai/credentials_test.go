@@ -0,0 +1,123 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeCredentials(t *testing.T, content string, perm os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), CredentialsFile)
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCredentialsAPIKey(t *testing.T) {
+	t.Setenv(EnvOpenAIAPIKey, "env-openai")
+	t.Setenv(EnvGeminiAPIKey, "")
+
+	counter := filepath.Join(t.TempDir(), "calls")
+	path := writeCredentials(t, `
+anthropic:
+  key_command: "echo call >> `+counter+`; printf 'cmd-anthropic\nsecond line\n'"
+deepseek:
+  key: file-deepseek
+`, 0600)
+
+	creds, err := LoadCredentials(path)
+	if err != nil {
+		t.Fatalf("LoadCredentials() error = %v", err)
+	}
+
+	tests := []struct {
+		provider string
+		want     string
+		wantErr  bool
+	}{
+		{"anthropic", "cmd-anthropic", false},
+		{"anthropic", "cmd-anthropic", false}, // cached, the command runs once
+		{"deepseek", "file-deepseek", false},
+		{"openai", "env-openai", false},
+		{"gemini", "", true},
+		{"unknown", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			got, err := creds.APIKey(tt.provider)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("APIKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("APIKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	calls, _ := os.ReadFile(counter)
+	if n := strings.Count(string(calls), "call"); n != 1 {
+		t.Errorf("key_command ran %d times, want 1", n)
+	}
+}
+
+func TestLoadCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		perm    os.FileMode
+		wantErr string
+	}{
+		{"readable by others", "openai:\n  key: k\n", 0644, "accessible by other users"},
+		{"unknown provider", "acme:\n  key: k\n", 0600, "unknown provider"},
+		{"key and command", "openai:\n  key: k\n  key_command: echo k\n", 0600, "both key and key_command"},
+		{"valid", "openai:\n  key: k\n", 0600, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadCredentials(writeCredentials(t, tt.content, tt.perm))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("LoadCredentials() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadCredentials() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := LoadCredentials(filepath.Join(t.TempDir(), "missing.yaml")); err != nil {
+		t.Errorf("LoadCredentials() of a missing file error = %v, want nil", err)
+	}
+}
+
+func TestNewClientResolvesKeyLazily(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "calls")
+	creds, err := LoadCredentials(writeCredentials(t, `
+anthropic:
+  key_command: "echo call >> `+counter+`; false"
+`, 0600))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClient(&Model{Name: "test", Provider: "anthropic"}, creds, nil)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if _, err := os.Stat(counter); !os.IsNotExist(err) {
+		t.Fatalf("NewClient() ran key_command, want it deferred to the first request")
+	}
+
+	if _, err := client.GenerateWithMessages(nil, "test", Parameters{}); err == nil || !strings.Contains(err.Error(), "key_command for anthropic failed") {
+		t.Errorf("GenerateWithMessages() error = %v, want the key_command failure", err)
+	}
+	if _, err := NewClient(&Model{Name: "test", Provider: "acme"}, creds, nil); err == nil {
+		t.Errorf("NewClient() of an unknown provider error = nil")
+	}
+}
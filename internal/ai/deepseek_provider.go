@@ -23,9 +23,11 @@ func NewDeepSeekProvider(
 
 // DeepSeekRequest defines the request structure specific to DeepSeek.
 type DeepSeekRequest struct {
-	Model     string    `json:"model"`
-	MaxTokens int       `json:"max_tokens"`
-	Messages  []Message `json:"messages"`
+	Model       string    `json:"model"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
+	Messages    []Message `json:"messages"`
 }
 
 // DeepSeekResponse defines the response structure specific to DeepSeek.
@@ -45,11 +47,16 @@ type DeepSeekResponse struct {
 }
 
 // GenerateResponse sends a request to DeepSeek's API and parses the response.
-func (p *DeepSeekProvider) GenerateResponse(messages []Message) (Response, error) {
+func (p *DeepSeekProvider) GenerateResponse(
+	messages []Message,
+	params Parameters,
+) (Response, error) {
 	reqPayload := DeepSeekRequest{
-		Model:     p.model.Name,
-		MaxTokens: 1024,
-		Messages:  messages,
+		Model:       p.model.Name,
+		MaxTokens:   params.GetMaxTokens(),
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+		Messages:    toAPIMessages(messages),
 	}
 
 	var apiResp DeepSeekResponse
@@ -19,9 +19,11 @@ func NewGeminiProvider(model *Model, apiKey string, client *http.Client) (*Gemin
 
 // GeminiRequest defines the request structure using OpenAI compatibility mode
 type GeminiRequest struct {
-	Model     string    `json:"model"`
-	MaxTokens int       `json:"max_tokens"`
-	Messages  []Message `json:"messages"`
+	Model       string    `json:"model"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
+	Messages    []Message `json:"messages"`
 }
 
 // GeminiResponse defines the response structure using OpenAI compatibility mode
@@ -39,11 +41,16 @@ type GeminiResponse struct {
 }
 
 // GenerateResponse sends a request to Gemini's API using OpenAI compatibility mode
-func (p *GeminiProvider) GenerateResponse(messages []Message) (Response, error) {
+func (p *GeminiProvider) GenerateResponse(
+	messages []Message,
+	params Parameters,
+) (Response, error) {
 	reqPayload := GeminiRequest{
-		Model:     p.model.Name,
-		MaxTokens: 1024,
-		Messages:  messages,
+		Model:       p.model.Name,
+		MaxTokens:   params.GetMaxTokens(),
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+		Messages:    toAPIMessages(messages),
 	}
 
 	var apiResp GeminiResponse
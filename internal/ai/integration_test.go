@@ -80,7 +80,7 @@ func TestIntegrationRequests(t *testing.T) {
 			}
 
 			// Create client
-			client, err := NewClient(model, nil)
+			client, err := NewClient(model, nil, nil)
 			if err != nil {
 				t.Fatalf("Failed to create client: %v", err)
 			}
@@ -89,6 +89,7 @@ func TestIntegrationRequests(t *testing.T) {
 			response, err := client.GenerateWithMessages(
 				[]Message{*NewUserMessage(tt.prompt)},
 				"test",
+				Parameters{},
 			)
 			if err != nil {
 				t.Fatalf("Failed to generate response: %v", err)
@@ -41,18 +41,18 @@ func (m *MockAIClient) EXPECT() *MockAIClientMockRecorder {
 }
 
 // GenerateWithMessages mocks base method.
-func (m *MockAIClient) GenerateWithMessages(messages []Message, command string) (Response, error) {
+func (m *MockAIClient) GenerateWithMessages(messages []Message, command string, params Parameters) (Response, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateWithMessages", messages, command)
+	ret := m.ctrl.Call(m, "GenerateWithMessages", messages, command, params)
 	ret0, _ := ret[0].(Response)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GenerateWithMessages indicates an expected call of GenerateWithMessages.
-func (mr *MockAIClientMockRecorder) GenerateWithMessages(messages, command any) *gomock.Call {
+func (mr *MockAIClientMockRecorder) GenerateWithMessages(messages, command, params any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateWithMessages", reflect.TypeOf((*MockAIClient)(nil).GenerateWithMessages), messages, command)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateWithMessages", reflect.TypeOf((*MockAIClient)(nil).GenerateWithMessages), messages, command, params)
 }
 
 // MockProvider is a mock of Provider interface.
@@ -80,18 +80,18 @@ func (m *MockProvider) EXPECT() *MockProviderMockRecorder {
 }
 
 // GenerateResponse mocks base method.
-func (m *MockProvider) GenerateResponse(messages []Message) (Response, error) {
+func (m *MockProvider) GenerateResponse(messages []Message, params Parameters) (Response, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateResponse", messages)
+	ret := m.ctrl.Call(m, "GenerateResponse", messages, params)
 	ret0, _ := ret[0].(Response)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GenerateResponse indicates an expected call of GenerateResponse.
-func (mr *MockProviderMockRecorder) GenerateResponse(messages any) *gomock.Call {
+func (mr *MockProviderMockRecorder) GenerateResponse(messages, params any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateResponse", reflect.TypeOf((*MockProvider)(nil).GenerateResponse), messages)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateResponse", reflect.TypeOf((*MockProvider)(nil).GenerateResponse), messages, params)
 }
 
 // MockAIConversation is a mock of AIConversation interface.
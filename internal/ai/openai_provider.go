@@ -19,9 +19,11 @@ func NewOpenAIProvider(model *Model, apiKey string, client *http.Client) (*OpenA
 
 // OpenAIRequest defines the request structure specific to OpenAI.
 type OpenAIRequest struct {
-	Model     string    `json:"model"`
-	MaxTokens int       `json:"max_tokens"`
-	Messages  []Message `json:"messages"`
+	Model       string    `json:"model"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
+	Messages    []Message `json:"messages"`
 }
 
 // OpenAIResponse defines the response structure specific to OpenAI.
@@ -42,11 +44,16 @@ type OpenAIResponse struct {
 }
 
 // GenerateResponse sends a request to OpenAI's API and parses the response.
-func (p *OpenAIProvider) GenerateResponse(messages []Message) (Response, error) {
+func (p *OpenAIProvider) GenerateResponse(
+	messages []Message,
+	params Parameters,
+) (Response, error) {
 	reqPayload := OpenAIRequest{
-		Model:     p.model.Name,
-		MaxTokens: 1024,
-		Messages:  messages,
+		Model:       p.model.Name,
+		MaxTokens:   params.GetMaxTokens(),
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+		Messages:    toAPIMessages(messages),
 	}
 
 	var apiResp OpenAIResponse
@@ -23,9 +23,11 @@ func NewOpenRouterProvider(
 
 // OpenRouterRequest defines the request structure specific to OpenRouter.
 type OpenRouterRequest struct {
-	Model     string    `json:"model"`
-	MaxTokens int       `json:"max_tokens"`
-	Messages  []Message `json:"messages"`
+	Model       string    `json:"model"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
+	Messages    []Message `json:"messages"`
 }
 
 // OpenRouterResponse defines the response structure specific to OpenRouter.
@@ -46,11 +48,16 @@ type OpenRouterResponse struct {
 }
 
 // GenerateResponse sends a request to OpenRouter's API and parses the response.
-func (p *OpenRouterProvider) GenerateResponse(messages []Message) (Response, error) {
+func (p *OpenRouterProvider) GenerateResponse(
+	messages []Message,
+	params Parameters,
+) (Response, error) {
 	reqPayload := OpenRouterRequest{
-		Model:     p.model.Name,
-		MaxTokens: 1024,
-		Messages:  messages,
+		Model:       p.model.Name,
+		MaxTokens:   params.GetMaxTokens(),
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+		Messages:    toAPIMessages(messages),
 	}
 
 	var apiResp OpenRouterResponse
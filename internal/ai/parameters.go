@@ -0,0 +1,86 @@
+package ai
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const defaultMaxTokens = 1024
+
+// Parameters holds generation settings sent with each request.
+// Unset fields fall back to provider defaults.
+type Parameters struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+}
+
+// ParameterNames lists the parameters accepted by Set
+var ParameterNames = []string{"temperature", "top_p", "max_tokens"}
+
+// GetMaxTokens returns the maximum number of tokens to generate
+func (p Parameters) GetMaxTokens() int {
+	if p.MaxTokens > 0 {
+		return p.MaxTokens
+	}
+	return defaultMaxTokens
+}
+
+// Set parses and assigns a parameter by name. The value "default" unsets it.
+func (p *Parameters) Set(name, value string) error {
+	reset := strings.EqualFold(value, "default")
+
+	switch name {
+	case "temperature", "top_p":
+		var v *float64
+		if !reset {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s: %s", name, value)
+			}
+			if f < 0 || (name == "top_p" && f > 1) || f > 2 {
+				return fmt.Errorf("%s out of range: %s", name, value)
+			}
+			v = &f
+		}
+		if name == "temperature" {
+			p.Temperature = v
+		} else {
+			p.TopP = v
+		}
+	case "max_tokens":
+		if reset {
+			p.MaxTokens = 0
+			return nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid value for %s: %s", name, value)
+		}
+		p.MaxTokens = n
+	default:
+		return fmt.Errorf(
+			"unknown parameter: %s (available: %s)",
+			name,
+			strings.Join(ParameterNames, ", "),
+		)
+	}
+	return nil
+}
+
+// String returns a human readable summary of the parameters
+func (p Parameters) String() string {
+	format := func(v *float64) string {
+		if v == nil {
+			return "default"
+		}
+		return strconv.FormatFloat(*v, 'g', -1, 64)
+	}
+	return fmt.Sprintf(
+		"temperature=%s top_p=%s max_tokens=%d",
+		format(p.Temperature),
+		format(p.TopP),
+		p.GetMaxTokens(),
+	)
+}
@@ -0,0 +1,79 @@
+package ai
+
+import "testing"
+
+func TestParametersSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		param   string
+		value   string
+		wantErr bool
+		want    string
+	}{
+		{
+			name:  "Temperature",
+			param: "temperature",
+			value: "0.2",
+			want:  "temperature=0.2 top_p=default max_tokens=1024",
+		},
+		{
+			name:  "Top P",
+			param: "top_p",
+			value: "0.9",
+			want:  "temperature=default top_p=0.9 max_tokens=1024",
+		},
+		{
+			name:  "Max Tokens",
+			param: "max_tokens",
+			value: "4096",
+			want:  "temperature=default top_p=default max_tokens=4096",
+		},
+		{
+			name:  "Reset To Default",
+			param: "temperature",
+			value: "default",
+			want:  "temperature=default top_p=default max_tokens=1024",
+		},
+		{
+			name:    "Temperature Out Of Range",
+			param:   "temperature",
+			value:   "3",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid Max Tokens",
+			param:   "max_tokens",
+			value:   "-1",
+			wantErr: true,
+		},
+		{
+			name:    "Unknown Parameter",
+			param:   "seed",
+			value:   "1",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params Parameters
+			err := params.Set(tt.param, tt.value)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Set() error = nil, wantErr = true")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Set() unexpected error = %v", err)
+				return
+			}
+
+			if got := params.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,5 +1,5 @@
 package ai
 
 type Provider interface {
-	GenerateResponse(messages []Message) (Response, error)
+	GenerateResponse(messages []Message, params Parameters) (Response, error)
 }
@@ -96,8 +96,11 @@ func TestProviderEnvironmentVariables(t *testing.T) {
 			// Create mock client
 			_ = NewMockAIClient(ctrl)
 
-			// Create new client
-			client, err := NewClient(model, nil)
+			// Create new client, its API key is resolved by the first request
+			client, err := NewClient(model, nil, nil)
+			if err == nil {
+				_, err = client.getProvider()
+			}
 
 			// Check error cases
 			if tt.wantErr {
@@ -11,6 +11,18 @@ type Request struct {
 type Message struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// Model records which model produced an assistant message. It is kept in saved
+	// sessions but never sent to providers.
+	Model string `json:"model,omitempty"`
+}
+
+// toAPIMessages returns a copy of messages with local metadata removed
+func toAPIMessages(messages []Message) []Message {
+	apiMessages := make([]Message, len(messages))
+	for i, msg := range messages {
+		apiMessages[i] = Message{Role: msg.Role, Content: msg.Content}
+	}
+	return apiMessages
 }
 
 func NewUserMessage(content string) *Message {
@@ -0,0 +1,75 @@
+// Package ask collects the values of ask variables by asking the user on the terminal
+package ask
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/y0ug/ai-helper/internal/config"
+	"github.com/y0ug/ai-helper/internal/io"
+)
+
+// Terminal functions, replaced in tests
+var (
+	hasTerminal = io.HasTerminal
+	askText     = io.Ask
+	choose      = io.Choose
+	confirm     = io.ConfirmDefault
+	editText    = io.EditText
+)
+
+// Variables returns the values of the ask variables of vars that are not in answered,
+// e.g. because they were given on the command line. Without a terminal, the defaults
+// are used and a variable without default is an error.
+func Variables(vars []config.Variable, answered map[string]interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for _, v := range vars {
+		if v.Type != "ask" {
+			continue
+		}
+		if _, ok := answered[v.Name]; ok {
+			continue
+		}
+
+		value, err := question(v)
+		if err != nil {
+			return nil, err
+		}
+		values[v.Name] = value
+	}
+	return values, nil
+}
+
+// question asks for the value of a single variable
+func question(v config.Variable) (interface{}, error) {
+	def := ""
+	if v.Default != nil {
+		def = fmt.Sprint(v.Default)
+	}
+
+	if !hasTerminal() {
+		if v.Default == nil {
+			return nil, fmt.Errorf("no terminal to ask for %s, pass --param %s=VALUE", v.Name, v.Name)
+		}
+		return v.ParseAnswer(def)
+	}
+
+	q := v.Question
+	if q == "" {
+		q = v.Name
+	}
+
+	switch v.Ask {
+	case "choice":
+		return choose(q, v.Choices, def)
+	case "confirm":
+		b, _ := strconv.ParseBool(def)
+		return confirm(q, b)
+	case "editor":
+		fmt.Fprintf(os.Stderr, "%s (opening %s)\n", q, io.GetEditor())
+		return editText(def)
+	default:
+		return askText(q, def)
+	}
+}
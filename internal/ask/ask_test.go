@@ -0,0 +1,91 @@
+package ask
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/y0ug/ai-helper/internal/config"
+)
+
+// stubTerminal restores the terminal functions the test replaces when it ends
+func stubTerminal(t *testing.T) {
+	t.Helper()
+	origHasTerminal, origAskText, origChoose, origConfirm, origEditText := hasTerminal, askText, choose, confirm, editText
+	t.Cleanup(func() {
+		hasTerminal, askText, choose, confirm, editText = origHasTerminal, origAskText, origChoose, origConfirm, origEditText
+	})
+}
+
+func TestVariables(t *testing.T) {
+	vars := []config.Variable{
+		{Name: "Branch", Type: "exec", Exec: "git branch"},
+		{Name: "Title", Type: "ask", Question: "Bug title"},
+		{Name: "Severity", Type: "ask", Ask: "choice", Choices: []string{"low", "high"}, Default: "low"},
+		{Name: "Regression", Type: "ask", Ask: "confirm", Default: true},
+		{Name: "Steps", Type: "ask", Ask: "editor", Default: "1."},
+	}
+
+	var asked []string
+	stubTerminal(t)
+	hasTerminal = func() bool { return true }
+	askText = func(q, def string) (string, error) {
+		asked = append(asked, "text:"+q)
+		return "Crash on start", nil
+	}
+	choose = func(q string, choices []string, def string) (string, error) {
+		asked = append(asked, "choice:"+q+":"+def)
+		return choices[1], nil
+	}
+	confirm = func(q string, def bool) (bool, error) {
+		asked = append(asked, "confirm:"+q)
+		return def, nil
+	}
+	editText = func(content string) (string, error) {
+		asked = append(asked, "editor:"+content)
+		return content + " run it", nil
+	}
+
+	got, err := Variables(vars, map[string]interface{}{"Severity": "high"})
+	if err != nil {
+		t.Fatalf("Variables() error = %v", err)
+	}
+
+	want := map[string]interface{}{"Title": "Crash on start", "Regression": true, "Steps": "1. run it"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Variables() = %v, want %v", got, want)
+	}
+	if strings.Join(asked, ",") != "text:Bug title,confirm:Regression,editor:1." {
+		t.Errorf("asked %v, want the unanswered questions only", asked)
+	}
+}
+
+func TestVariablesWithoutTerminal(t *testing.T) {
+	stubTerminal(t)
+	hasTerminal = func() bool { return false }
+
+	tests := []struct {
+		name    string
+		v       config.Variable
+		want    interface{}
+		wantErr string
+	}{
+		{"default", config.Variable{Name: "Title", Type: "ask", Default: "untitled"}, "untitled", ""},
+		{"confirm default", config.Variable{Name: "Ok", Type: "ask", Ask: "confirm", Default: false}, false, ""},
+		{"no default", config.Variable{Name: "Title", Type: "ask"}, nil, "pass --param Title=VALUE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Variables([]config.Variable{tt.v}, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Variables() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got[tt.v.Name] != tt.want {
+				t.Errorf("Variables() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/y0ug/ai-helper/internal/prompt"
+	"github.com/y0ug/ai-helper/internal/secret"
+)
+
+// attachment is content added to the conversation with /file or /run. It is queued
+// until the next user message, then stays listed so it can still be dropped.
+type attachment struct {
+	Name    string // file path, or the command that produced the content
+	Path    string // set for files, also the key in the agent's TemplateData.Files
+	Content string
+	Message int // index of the user message it was sent with, -1 while queued
+
+	// Text of the attachment in that message, at Start. Secrets are redacted from it
+	// before it is sent so the message scan leaves it, and the position, unchanged.
+	Text  string
+	Start int
+}
+
+const attachmentPending = -1
+
+// format renders the attachment as it is sent to the model
+func (a attachment) format() string {
+	if a.Path != "" {
+		return fmt.Sprintf("File: %s\n%s", a.Path, prompt.FormatFile(a.Path, a.Content))
+	}
+	return fmt.Sprintf("Output of `%s`:\n```\n%s\n```", a.Name, a.Content)
+}
+
+// attachFiles loads files matching the given patterns and queues them for the next message
+func (c *Chat) attachFiles(patterns []string) error {
+	paths, err := prompt.ExpandFiles(patterns)
+	if err != nil {
+		return err
+	}
+	if err := c.agent.TemplateData.LoadFiles(paths); err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		c.attachments = append(c.attachments, attachment{
+			Name:    path,
+			Path:    path,
+			Content: c.agent.TemplateData.Files[path],
+			Message: attachmentPending,
+		})
+		fmt.Printf("Attached %s\n", path)
+	}
+	return nil
+}
+
+// runCommand runs a shell command after confirmation and queues its output for the next message
+func (c *Chat) runCommand(command string) error {
+	ok, err := c.confirm(fmt.Sprintf("Run `%s`?", command))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Command not run.")
+		return nil
+	}
+
+	out, err := exec.Command("sh", "-c", command).CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if err != nil {
+		// Keep the output of failing commands, it is often what the user wants to ask about
+		output = fmt.Sprintf("%s\n(%v)", output, err)
+	}
+
+	c.attachments = append(c.attachments, attachment{
+		Name:    command,
+		Content: output,
+		Message: attachmentPending,
+	})
+	fmt.Println(output)
+	fmt.Printf("Attached output of `%s`\n", command)
+	return nil
+}
+
+// dropAttachments removes the attachment at the given 1-based position, or all of them.
+// Content already sent is replaced by a short note in the message that carried it.
+func (c *Chat) dropAttachments(arg string) error {
+	if len(c.attachments) == 0 {
+		return fmt.Errorf("nothing attached")
+	}
+
+	if arg == "" {
+		var errs []error
+		for i := len(c.attachments) - 1; i >= 0; i-- {
+			if err := c.dropAttachment(i); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+		fmt.Println("All attachments dropped.")
+		return nil
+	}
+
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 || n > len(c.attachments) {
+		return fmt.Errorf("invalid attachment number: %s", arg)
+	}
+	name := c.attachments[n-1].Name
+	if err := c.dropAttachment(n - 1); err != nil {
+		return err
+	}
+	fmt.Printf("Dropped %s\n", name)
+	return nil
+}
+
+// dropAttachment removes an attachment from the queue or from the message it was sent
+// with. It fails, keeping the attachment, when its text is no longer in the message,
+// e.g. after /edit.
+func (c *Chat) dropAttachment(i int) error {
+	dropped := c.attachments[i]
+
+	if dropped.Message != attachmentPending {
+		if dropped.Message >= len(c.agent.Messages) {
+			return fmt.Errorf("the message %s was sent with is gone", dropped.Name)
+		}
+		msg := &c.agent.Messages[dropped.Message]
+		end := dropped.Start + len(dropped.Text)
+		if end > len(msg.Content) || msg.Content[dropped.Start:end] != dropped.Text {
+			return fmt.Errorf("%s was changed in its message, remove it with /edit", dropped.Name)
+		}
+		note := fmt.Sprintf("[%s removed]", dropped.Name)
+		msg.Content = msg.Content[:dropped.Start] + note + msg.Content[end:]
+
+		// Attachments after it in the same message moved
+		for j := range c.attachments {
+			a := &c.attachments[j]
+			if a.Message == dropped.Message && a.Start >= end {
+				a.Start += len(note) - len(dropped.Text)
+			}
+		}
+	}
+	c.attachments = append(c.attachments[:i], c.attachments[i+1:]...)
+
+	if dropped.Path == "" {
+		return nil
+	}
+	for _, a := range c.attachments {
+		if a.Path == dropped.Path {
+			return nil
+		}
+	}
+	delete(c.agent.TemplateData.Files, dropped.Path)
+	return nil
+}
+
+// listAttachments prints the attachments of the conversation
+func (c *Chat) listAttachments() {
+	if len(c.attachments) == 0 {
+		fmt.Println("Nothing attached.")
+		return
+	}
+	for i, a := range c.attachments {
+		status := "sent"
+		if a.Message == attachmentPending {
+			status = "queued"
+		}
+		fmt.Printf("  %d. %s (%d bytes, %s)\n", i+1, a.Name, len(a.Content), status)
+	}
+}
+
+// withAttachments prepends queued attachments to a message about to be added to the
+// conversation and marks them as sent
+func (c *Chat) withAttachments(message string) string {
+	var sb strings.Builder
+	for i := range c.attachments {
+		a := &c.attachments[i]
+		if a.Message != attachmentPending {
+			continue
+		}
+
+		a.Text = a.format()
+		if c.secretPolicy() == secret.PolicyRedact {
+			var findings []secret.Finding
+			a.Text, findings = c.agent.RedactText(a.Text)
+			for _, f := range findings {
+				fmt.Printf("Warning: possible secret %s redacted from %s\n", f, a.Name)
+			}
+		}
+		a.Message = len(c.agent.Messages)
+		a.Start = sb.Len()
+		sb.WriteString(a.Text)
+		sb.WriteString("\n\n")
+	}
+
+	sb.WriteString(message)
+	return sb.String()
+}
+
+// moveAttachments reassigns attachments sent with messages from index from onwards,
+// after those messages were removed. Use attachmentPending to queue them again.
+func (c *Chat) moveAttachments(from, to int) {
+	for i := range c.attachments {
+		if c.attachments[i].Message >= from {
+			c.attachments[i].Message = to
+		}
+	}
+}
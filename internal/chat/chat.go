@@ -1,13 +1,17 @@
 package chat
 
 import (
-	"bufio"
 	"fmt"
-	"os"
+	goio "io"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/y0ug/ai-helper/internal/ai"
+	"github.com/y0ug/ai-helper/internal/config"
+	"github.com/y0ug/ai-helper/internal/io"
+	"github.com/y0ug/ai-helper/internal/secret"
+	"github.com/y0ug/ai-helper/internal/stats"
 )
 
 type ChatHistory struct {
@@ -27,93 +31,237 @@ type SessionStats struct {
 }
 
 type Chat struct {
-	agent *ai.Agent
-	stats SessionStats
+	Render bool // print answers as styled Markdown
+
+	agent         *ai.Agent
+	input         *Input
+	attachments   []attachment
+	config        *config.Config
+	stats         SessionStats
+	infoProviders *ai.InfoProviders
+	credentials   *ai.Credentials
+	statsTracker  *stats.Tracker
 }
 
-func NewChat(agent *ai.Agent) *Chat {
+func NewChat(
+	agent *ai.Agent,
+	cfg *config.Config,
+	infoProviders *ai.InfoProviders,
+	credentials *ai.Credentials,
+	statsTracker *stats.Tracker,
+) *Chat {
 	return &Chat{
-		agent: agent,
+		agent:         agent,
+		config:        cfg,
+		infoProviders: infoProviders,
+		credentials:   credentials,
+		statsTracker:  statsTracker,
 	}
 }
 
 func (c *Chat) Start() error {
-	reader := bufio.NewReader(os.Stdin)
+	input, err := NewInput(filepath.Join(io.GetCacheDir(), "chat_history"))
+	if err != nil {
+		return fmt.Errorf("failed to initialize input: %w", err)
+	}
+	defer input.Close()
+	c.input = input
+
 	fmt.Println("Interactive chat mode. Commands:")
-	fmt.Println("  /exit, /quit - End session")
-	fmt.Println("  /reset       - Clear current conversation")
-	fmt.Println("  /history     - Show chat history")
-	fmt.Println("  /sessions    - List active sessions")
-	fmt.Println("  /resume ID   - Resume session by ID")
-	fmt.Printf("\nSession ID: %s\n", c.agent.ID)
-	fmt.Print("\n> ")
+	fmt.Println("  /exit, /quit   - End session")
+	fmt.Println("  /reset         - Clear current conversation")
+	fmt.Println("  /history       - Show chat history")
+	fmt.Println("  /sessions      - List active sessions")
+	fmt.Println("  /resume ID     - Resume session by ID")
+	fmt.Println("  /retry [MODEL] - Regenerate the last answer, optionally with another model")
+	fmt.Println("  /undo          - Drop the last question and its answer")
+	fmt.Println("  /edit          - Edit the last question in $EDITOR and resend it")
+	fmt.Println("  /compose       - Write a new message in $EDITOR")
+	fmt.Println("  /model [NAME]  - Show or switch the model, keeping the conversation")
+	fmt.Println("  /set [K] [V]   - Show or set a parameter (temperature, top_p, max_tokens)")
+	fmt.Println("  /file PATH...  - Attach files (globs allowed) to the next message")
+	fmt.Println("  /run CMD       - Attach the output of a shell command to the next message")
+	fmt.Println("  /attached      - List attachments")
+	fmt.Println("  /drop [N]      - Remove attachment N, or all of them")
+	fmt.Println("  /cmd [NAME]    - List commands, or run a configured command: /cmd NAME [input]")
+	fmt.Println("  /copy [N]      - Copy code block N of the last answer to the clipboard")
+	fmt.Println("  /save N PATH   - Save code block N of the last answer to a file")
+	fmt.Println(`Wrap text in """ lines or end lines with Alt-Enter to write several lines.`)
+	fmt.Printf("\nSession ID: %s\n\n", c.agent.ID)
+
+	// Answer a prompt seeded before the chat started, e.g. by a command
+	if msgs := c.agent.GetMessages(); len(msgs) > 0 && msgs[len(msgs)-1].Role == "user" {
+		c.send(c.agent.Client, c.agent.Model)
+		fmt.Println()
+	}
 
 	for {
-		input, err := reader.ReadString('\n')
+		message, err := input.ReadMessage()
+		if err == goio.EOF {
+			fmt.Println()
+			return c.agent.Save()
+		}
 		if err != nil {
 			return fmt.Errorf("error reading input: %w", err)
 		}
 
-		input = strings.TrimSpace(input)
+		if message == "" {
+			continue
+		}
 
 		// Handle commands
-		if strings.HasPrefix(input, "/") {
-			if err := c.handleCommand(input); err != nil {
+		if strings.HasPrefix(message, "/") {
+			if err := c.handleCommand(message); err != nil {
 				fmt.Printf("Error: %v\n", err)
-				fmt.Print("\n> ")
-				continue
 			}
-			if input == "/exit" || input == "/quit" {
+			if message == "/exit" || message == "/quit" {
 				return c.agent.Save()
 			}
+			fmt.Println()
 			continue
 		}
 
-		if input == "" {
-			fmt.Print("> ")
-			continue
+		// Add user message to agent
+		c.agent.AddMessage("user", c.withAttachments(message))
+
+		c.send(c.agent.Client, c.agent.Model)
+		fmt.Println()
+	}
+}
+
+// secretPolicy returns the secret policy of the loaded command, redact by default
+func (c *Chat) secretPolicy() secret.Policy {
+	if c.agent.Command == nil {
+		return secret.PolicyRedact
+	}
+	policy, _ := secret.ParsePolicy(c.agent.Command.Secrets)
+	return policy
+}
+
+// send requests an answer for the current conversation and prints it with session stats
+func (c *Chat) send(client ai.AIClient, model *ai.Model) {
+	// Keep secrets from being sent, following the policy of the loaded command
+	policy := c.secretPolicy()
+	findings, err := c.agent.ScanSecrets(policy)
+	if err != nil {
+		fmt.Printf("Error: %v\nUse /edit or /undo to change the message.\n", err)
+		return
+	}
+	for _, f := range findings {
+		if policy == secret.PolicyRedact {
+			fmt.Printf("Warning: possible secret %s redacted\n", f)
+		} else {
+			fmt.Printf("Warning: possible secret %s sent as is\n", f)
 		}
+	}
 
-		// Add user message to agent
-		c.agent.AddMessage("user", input)
+	// Generate response using the agent
+	resp, err := c.agent.SendRequestWith(client, model)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Println()
+	io.PrintMarkdown(resp.Content, c.Render)
+	c.updateStats(resp)
+
+	fmt.Printf("\nModel %s | Tokens: %d sent (%d cached), %d received\n",
+		model.Name,
+		c.stats.SentTokens,
+		c.stats.CacheHitTokens,
+		c.stats.ReceivedTokens)
+	fmt.Printf("Cost: $%.4f message, $%.4f session.\n",
+		c.stats.MessageCost,
+		c.stats.TotalCost)
+}
+
+// updateStats adds a response's usage to the session stats. Like the agent totals,
+// usage from answers dropped by /retry, /undo or /edit stays counted since it was billed.
+func (c *Chat) updateStats(resp ai.Response) {
+	c.stats.SentTokens += resp.InputTokens
+	c.stats.ReceivedTokens += resp.OutputTokens
+
+	c.stats.MessageCost = 0
+	if resp.Cost != nil {
+		c.stats.MessageCost = *resp.Cost
+		c.stats.TotalCost += *resp.Cost
+	}
+
+	// Calculate cache metrics
+	newCacheHits := 0
+	if resp.InputTokens >= 1024 {
+		// Round down to nearest 128 token increment
+		newCacheHits = (resp.CachedTokens / 128) * 128
+	}
+	c.stats.CacheHitTokens += newCacheHits
+	c.stats.CacheWriteTokens += resp.InputTokens - newCacheHits
+}
 
-		// Generate response using the agent
-		resp, err := c.agent.SendRequest()
+// confirm asks a yes/no question, defaulting to no
+func (c *Chat) confirm(question string) (bool, error) {
+	answer, err := c.input.ReadLine(question + " [y/N] ")
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes"), nil
+}
+
+// newClient resolves a model name and creates a client for it
+func (c *Chat) newClient(modelName string) (*ai.Model, *ai.Client, error) {
+	model, err := ai.ParseModel(modelName, c.infoProviders)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse model: %w", err)
+	}
+	client, err := ai.NewClient(model, c.credentials, c.statsTracker)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	return model, client, nil
+}
+
+// retry regenerates the last answer, using modelName instead of the session model if set
+func (c *Chat) retry(modelName string) error {
+	client := c.agent.Client
+	model := c.agent.Model
+	if modelName != "" {
+		var err error
+		model, client, err = c.newClient(modelName)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			fmt.Print("\n> ")
-			continue
+			return err
 		}
+	}
 
-		fmt.Printf("\n%s\n", resp.Content)
-		// Update session stats
-		c.stats.SentTokens += resp.InputTokens
-		c.stats.ReceivedTokens += resp.OutputTokens
+	if err := c.agent.DropLastResponse(); err != nil {
+		return err
+	}
+	c.send(client, model)
+	return nil
+}
 
-		if resp.Cost != nil {
-			c.stats.MessageCost = *resp.Cost
-			c.stats.TotalCost += *resp.Cost
-		}
+// edit opens the last user message in the editor and resends it in place of the original
+func (c *Chat) edit() error {
+	content, err := c.agent.LastUserMessage()
+	if err != nil {
+		return err
+	}
 
-		// Calculate cache metrics
-		newCacheHits := 0
-		if resp.InputTokens >= 1024 {
-			// Round down to nearest 128 token increment
-			newCacheHits = (resp.CachedTokens / 128) * 128
-		}
-		c.stats.CacheHitTokens += newCacheHits
-		c.stats.CacheWriteTokens += resp.InputTokens - newCacheHits
+	edited, err := io.EditText(content)
+	if err != nil {
+		return err
+	}
+	if edited == "" {
+		return fmt.Errorf("empty message, edit aborted")
+	}
 
-		fmt.Printf("\nModel %s | Tokens: %d sent (%d cached), %d received\n",
-			c.agent.Model.Name,
-			c.stats.SentTokens,
-			c.stats.CacheHitTokens,
-			c.stats.ReceivedTokens)
-		fmt.Printf("Cost: $%.4f message, $%.4f session.\n",
-			c.stats.MessageCost,
-			c.stats.TotalCost)
-		fmt.Print("\n> ")
+	if err := c.agent.Undo(); err != nil {
+		return err
 	}
+	// Attachments sent with the original message are part of the edited text
+	c.moveAttachments(len(c.agent.Messages), len(c.agent.Messages))
+	c.agent.AddMessage("user", edited)
+	c.send(c.agent.Client, c.agent.Model)
+	return nil
 }
 
 func (c *Chat) handleCommand(cmd string) error {
@@ -123,6 +271,7 @@ func (c *Chat) handleCommand(cmd string) error {
 		return nil
 	case "/reset":
 		c.agent.Messages = nil
+		c.attachments = nil
 		fmt.Println("Conversation cleared.")
 	case "/history":
 		fmt.Println("\nChat History:")
@@ -130,6 +279,9 @@ func (c *Chat) handleCommand(cmd string) error {
 			if h.Role == "user" {
 				fmt.Printf("> ")
 			}
+			if h.Model != "" {
+				fmt.Printf("[%s] ", h.Model)
+			}
 			fmt.Printf("%s\n", h.Content)
 		}
 
@@ -151,10 +303,132 @@ func (c *Chat) handleCommand(cmd string) error {
 		if err != nil {
 			return fmt.Errorf("session not found: %w", err)
 		}
+		newAgent.Client = c.agent.Client
+		newAgent.SessionRules = c.agent.SessionRules
+		newAgent.Strict = c.agent.Strict
 		c.agent = newAgent
+		c.attachments = nil
+	case "/retry":
+		if len(parts) > 2 {
+			return fmt.Errorf("usage: /retry [MODEL]")
+		}
+		modelName := ""
+		if len(parts) == 2 {
+			modelName = parts[1]
+		}
+		if err := c.retry(modelName); err != nil {
+			return err
+		}
+	case "/undo":
+		if err := c.agent.Undo(); err != nil {
+			return err
+		}
+		c.moveAttachments(len(c.agent.Messages), attachmentPending)
+		fmt.Println("Last exchange removed.")
+	case "/edit":
+		if err := c.edit(); err != nil {
+			return err
+		}
+	case "/model":
+		if len(parts) == 1 {
+			fmt.Printf("Model: %s\n", c.agent.Model)
+			return nil
+		}
+		if len(parts) != 2 {
+			return fmt.Errorf("usage: /model [NAME]")
+		}
+		model, client, err := c.newClient(parts[1])
+		if err != nil {
+			return err
+		}
+		c.agent.SetModel(model, client)
+		fmt.Printf("Switched to model %s\n", model)
+	case "/set":
+		switch len(parts) {
+		case 1:
+			fmt.Printf("Parameters: %s\n", c.agent.Parameters)
+		case 3:
+			if err := c.agent.Parameters.Set(parts[1], parts[2]); err != nil {
+				return err
+			}
+			fmt.Printf("Parameters: %s\n", c.agent.Parameters)
+		default:
+			return fmt.Errorf("usage: /set [NAME VALUE|default]")
+		}
+	case "/compose":
+		message, err := io.EditText("")
+		if err != nil {
+			return err
+		}
+		if message == "" {
+			return fmt.Errorf("empty message, nothing sent")
+		}
+		c.agent.AddMessage("user", c.withAttachments(message))
+		c.send(c.agent.Client, c.agent.Model)
+	case "/file":
+		if len(parts) < 2 {
+			return fmt.Errorf("usage: /file PATH...")
+		}
+		if err := c.attachFiles(parts[1:]); err != nil {
+			return err
+		}
+	case "/run":
+		command := strings.TrimSpace(strings.TrimPrefix(cmd, "/run"))
+		if command == "" {
+			return fmt.Errorf("usage: /run CMD")
+		}
+		if err := c.runCommand(command); err != nil {
+			return err
+		}
+	case "/cmd":
+		if len(parts) == 1 {
+			c.listCommands()
+			return nil
+		}
+		// Keep the input as typed rather than split on whitespace
+		name := parts[1]
+		var args []string
+		rest := strings.TrimSpace(strings.TrimPrefix(cmd, "/cmd"))
+		if input := strings.TrimSpace(strings.TrimPrefix(rest, name)); input != "" {
+			args = []string{input}
+		}
+		if err := c.ApplyCommand(name, args, nil, nil); err != nil {
+			return err
+		}
+		c.send(c.agent.Client, c.agent.Model)
+	case "/copy":
+		if len(parts) > 2 || len(parts) == 2 && !isBlockNumber(parts[1]) {
+			return fmt.Errorf("usage: /copy [N]")
+		}
+		arg := ""
+		if len(parts) == 2 {
+			arg = parts[1]
+		}
+		if err := c.copyBlock(arg); err != nil {
+			return err
+		}
+	case "/save":
+		if len(parts) != 3 || !isBlockNumber(parts[1]) {
+			return fmt.Errorf("usage: /save N PATH")
+		}
+		if err := c.saveBlock(parts[1], parts[2]); err != nil {
+			return err
+		}
+	case "/attached":
+		c.listAttachments()
+	case "/drop":
+		if len(parts) > 2 {
+			return fmt.Errorf("usage: /drop [N]")
+		}
+		arg := ""
+		if len(parts) == 2 {
+			arg = parts[1]
+		}
+		if err := c.dropAttachments(arg); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("unknown command: %s", cmd)
 	}
-	fmt.Print("\n> ")
 	return nil
 }
@@ -0,0 +1,91 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/y0ug/ai-helper/internal/io"
+	"github.com/y0ug/ai-helper/internal/markdown"
+)
+
+// isBlockNumber reports whether arg numbers a code block, counting from 1
+func isBlockNumber(arg string) bool {
+	n, err := strconv.Atoi(arg)
+	return err == nil && n >= 1
+}
+
+// lastCodeBlock returns code block n (1-based) of the last answer. With n empty, the
+// answer must contain a single block.
+func (c *Chat) lastCodeBlock(n string) (markdown.CodeBlock, error) {
+	var content string
+	for i := len(c.agent.Messages) - 1; i >= 0; i-- {
+		if c.agent.Messages[i].Role == "assistant" {
+			content = c.agent.Messages[i].Content
+			break
+		}
+	}
+	blocks := markdown.ExtractCodeBlocks(content)
+
+	if n == "" {
+		if len(blocks) > 1 {
+			return markdown.CodeBlock{}, fmt.Errorf(
+				"the last answer has %d code blocks, pick one with its number",
+				len(blocks),
+			)
+		}
+		n = "1"
+	}
+
+	idx, err := strconv.Atoi(n)
+	if err != nil {
+		return markdown.CodeBlock{}, fmt.Errorf("invalid code block number: %s", n)
+	}
+	selected, err := markdown.SelectCodeBlocks(blocks, "", idx)
+	if err != nil {
+		return markdown.CodeBlock{}, err
+	}
+	return selected[0], nil
+}
+
+// copyBlock copies a code block of the last answer to the clipboard
+func (c *Chat) copyBlock(n string) error {
+	block, err := c.lastCodeBlock(n)
+	if err != nil {
+		return err
+	}
+	if err := io.CopyToClipboard(block.Code); err != nil {
+		return err
+	}
+	fmt.Printf("Copied %d lines to the clipboard.\n", strings.Count(block.Code, "\n")+1)
+	return nil
+}
+
+// saveBlock writes a code block of the last answer to path, confirming before overwriting
+func (c *Chat) saveBlock(n, path string) error {
+	block, err := c.lastCodeBlock(n)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		ok, err := c.confirm(fmt.Sprintf("%s exists, overwrite?", path))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("File not saved.")
+			return nil
+		}
+	}
+
+	if err := io.EnsureDirectory(path); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(block.Code+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	fmt.Printf("Saved to %s\n", path)
+	return nil
+}
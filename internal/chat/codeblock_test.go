@@ -0,0 +1,46 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveBlock(t *testing.T) {
+	c := newTestChat()
+	c.agent.AddMessage("user", "code please")
+	c.agent.AddMessage("assistant", "```go\nfirst\n```\n\n```go\nsecond\n```")
+	path := filepath.Join(t.TempDir(), "out.go")
+
+	tests := []struct {
+		name    string
+		command string
+		wantErr string
+		want    string
+	}{
+		{"zero", "/save 0 " + path, "usage: /save N PATH", ""},
+		{"negative", "/save -1 " + path, "usage: /save N PATH", ""},
+		{"not a number", "/save x " + path, "usage: /save N PATH", ""},
+		{"copy zero", "/copy 0", "usage: /copy [N]", ""},
+		{"out of range", "/save 3 " + path, "code block 3 not found", ""},
+		{"second", "/save 2 " + path, "", "second\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.handleCommand(tt.command)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("%s error = %v, want %q", tt.command, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%s error = %v", tt.command, err)
+			}
+			if got, _ := os.ReadFile(path); string(got) != tt.want {
+				t.Errorf("saved %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
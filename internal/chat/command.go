@@ -0,0 +1,117 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/y0ug/ai-helper/internal/ask"
+	"github.com/y0ug/ai-helper/internal/config"
+	"github.com/y0ug/ai-helper/internal/hook"
+	"github.com/y0ug/ai-helper/internal/io"
+)
+
+// ApplyCommand renders a configured command into the conversation through the same
+// Agent.LoadCommand/ApplyCommand path as one-shot mode. The command's system prompt,
+// if any, replaces the current one and its prompt is added as a user message. Flags
+// are name=value parameters and files are loaded for the templates, like --param and
+// --files of one-shot mode.
+func (c *Chat) ApplyCommand(name string, args, flags, files []string) error {
+	cmd, ok := c.config.Commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command '%s'", name)
+	}
+
+	params, args, err := cmd.ParseParams(flags, args)
+	if err != nil {
+		return err
+	}
+
+	var input string
+	if cmd.ReadsInput() {
+		spec := cmd.InputSpec()
+		// Stdin carries the chat itself, so input comes from the other sources
+		var types []string
+		for _, t := range spec.Types {
+			if t != "stdin" {
+				types = append(types, t)
+			}
+		}
+		spec.Types = types
+
+		input, err = io.ReadInput(args, spec)
+		if err != nil {
+			return fmt.Errorf("error reading input: %w", err)
+		}
+	}
+
+	if err := hook.RunPre(cmd.Pre, hook.Data{Input: input}); err != nil {
+		return err
+	}
+
+	answers, err := ask.Variables(cmd.Variables, params)
+	if err != nil {
+		return err
+	}
+	for name, value := range answers {
+		params[name] = value
+	}
+	outputs, err := config.ExecVariables(cmd.Variables)
+	if err != nil {
+		return fmt.Errorf("error running variable commands: %w", err)
+	}
+	for name, value := range outputs {
+		params[name] = value
+	}
+
+	// Switch to the command's model, which then stays the session model
+	if cmd.Model != "" {
+		model, client, err := c.newClient(cmd.Model)
+		if err != nil {
+			return err
+		}
+		c.agent.SetModel(model, client)
+	}
+
+	// The system template is rendered by LoadCommand, with the same data as the prompt.
+	// Variables of a previous command are dropped, it may have set some this one does not.
+	c.agent.TemplateData.Vars = params
+	if err := c.agent.TemplateData.LoadFiles(files); err != nil {
+		return fmt.Errorf("error loading files: %w", err)
+	}
+
+	count := len(c.agent.Messages)
+	if err := c.agent.LoadCommand(&cmd); err != nil {
+		return fmt.Errorf("error loading command: %w", err)
+	}
+	// A system message inserted at the front shifts the messages attachments were sent with
+	if len(c.agent.Messages) > count {
+		for i := range c.attachments {
+			if c.attachments[i].Message != attachmentPending {
+				c.attachments[i].Message++
+			}
+		}
+	}
+
+	if err := c.agent.ApplyCommand(input); err != nil {
+		return fmt.Errorf("error applying command: %w", err)
+	}
+	return nil
+}
+
+// listCommands prints the configured commands available to /cmd
+func (c *Chat) listCommands() {
+	names := make([]string, 0, len(c.config.Commands))
+	for name := range c.config.Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println("Available commands:")
+	for _, name := range names {
+		if desc := c.config.Commands[name].Description; desc != "" {
+			fmt.Printf("  %-15s %s\n", name, desc)
+		} else {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+}
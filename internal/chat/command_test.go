@@ -0,0 +1,70 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/y0ug/ai-helper/internal/config"
+)
+
+func TestChatApplyCommand(t *testing.T) {
+	notes := filepath.Join(t.TempDir(), "notes.md")
+	if err := os.WriteFile(notes, []byte("ship it"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTestChat()
+	c.config = &config.Config{Commands: map[string]config.Command{
+		"review": {
+			Prompt: `{{ .Tone }} review, {{ fileContent "` + notes + `" }}`,
+			Params: []config.Param{{Name: "Tone", Type: "string", Default: "kind"}},
+		},
+		"summary": {Prompt: "summary{{ with .Vars.Tone }} {{ . }}{{ end }}"},
+	}}
+
+	if err := c.ApplyCommand("review", nil, []string{"Tone=strict"}, []string{notes}); err != nil {
+		t.Fatalf("ApplyCommand() error = %v", err)
+	}
+	if got, want := c.agent.Messages[0].Content, "strict review, ship it"; got != want {
+		t.Errorf("review prompt = %q, want %q", got, want)
+	}
+
+	if err := c.ApplyCommand("summary", nil, nil, nil); err != nil {
+		t.Fatalf("ApplyCommand() error = %v", err)
+	}
+	if got, want := c.agent.Messages[1].Content, "summary"; got != want {
+		t.Errorf("summary prompt = %q, want %q, without the variables of review", got, want)
+	}
+
+	if err := c.ApplyCommand("review", nil, []string{"Color=red"}, nil); err == nil {
+		t.Errorf("ApplyCommand() with an unknown parameter error = nil")
+	}
+}
+
+func TestChatApplyCommandAfterResume(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	c := newTestChat()
+	c.config = &config.Config{Commands: map[string]config.Command{
+		"summary": {Prompt: "summary of {{ .Vars.Topic }}", Params: []config.Param{{Name: "Topic"}}},
+		"typo":    {Prompt: "summary of {{ .Vars.Topc }}"},
+	}}
+	c.agent.Strict = true
+	c.agent.AddMessage("user", "hello")
+	if err := c.agent.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.handleCommand("/resume test"); err != nil {
+		t.Fatalf("/resume error = %v", err)
+	}
+	if err := c.ApplyCommand("typo", nil, nil, nil); err == nil {
+		t.Errorf("ApplyCommand() of a missing key after /resume error = nil, want strict to still apply")
+	}
+	if err := c.ApplyCommand("summary", nil, []string{"Topic=hooks"}, nil); err != nil {
+		t.Fatalf("ApplyCommand() after /resume error = %v", err)
+	}
+	if got, want := c.agent.Messages[len(c.agent.Messages)-1].Content, "summary of hooks"; got != want {
+		t.Errorf("prompt = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,92 @@
+package chat
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const maxHistoryEntries = 1000
+
+// InputHistory keeps previously entered chat lines, persisted one per line in a file
+type InputHistory struct {
+	path    string
+	entries []string
+}
+
+// LoadInputHistory reads the history file at path, keeping the most recent entries
+func LoadInputHistory(path string) (*InputHistory, error) {
+	h := &InputHistory{path: path}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return h, nil
+		}
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	if len(h.entries) > maxHistoryEntries {
+		h.entries = h.entries[len(h.entries)-maxHistoryEntries:]
+	}
+	return h, nil
+}
+
+// Add records a new entry and appends it to the history file.
+// Empty, multi-line and repeated entries are skipped.
+func (h *InputHistory) Add(entry string) {
+	if strings.TrimSpace(entry) == "" || strings.Contains(entry, "\n") {
+		return
+	}
+	if len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry {
+		return
+	}
+
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > maxHistoryEntries {
+		h.entries = h.entries[1:]
+	}
+
+	if h.path == "" {
+		return
+	}
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save history: %v\n", err)
+		return
+	}
+	defer file.Close()
+	if _, err := fmt.Fprintln(file, entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save history: %v\n", err)
+	}
+}
+
+// Len returns the number of entries in the history
+func (h *InputHistory) Len() int {
+	return len(h.entries)
+}
+
+// At returns an entry, index 0 being the most recent one
+func (h *InputHistory) At(idx int) string {
+	return h.entries[len(h.entries)-1-idx]
+}
+
+// terminalHistory exposes InputHistory to term.Terminal for recall only. The terminal adds
+// every line it reads, so complete messages are recorded by Input instead.
+type terminalHistory struct {
+	*InputHistory
+}
+
+func (terminalHistory) Add(string) {}
@@ -0,0 +1,167 @@
+package chat
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	promptPrimary      = "> "
+	promptContinuation = "... "
+	multiLineDelimiter = `"""`
+
+	// keyContinue replaces Alt-Enter in the input stream so it can be told apart from Enter
+	keyContinue = '\x1e'
+)
+
+// Input reads chat messages from the user. When stdin is a terminal it provides
+// line editing and history recall; otherwise it reads plain lines.
+//
+// A message spans several lines when it is wrapped in """ lines, when the lines
+// are pasted at once, or when lines are ended with Alt-Enter.
+type Input struct {
+	fd        int
+	terminal  *term.Terminal
+	reader    *bufio.Reader
+	history   *InputHistory
+	continued bool
+}
+
+// NewInput creates an Input reading from stdin, persisting history to historyFile
+func NewInput(historyFile string) (*Input, error) {
+	history, err := LoadInputHistory(historyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	in := &Input{
+		fd:      int(os.Stdin.Fd()),
+		history: history,
+	}
+
+	if !term.IsTerminal(in.fd) {
+		in.reader = bufio.NewReader(os.Stdin)
+		return in, nil
+	}
+
+	rw := struct {
+		io.Reader
+		io.Writer
+	}{&altEnterReader{os.Stdin}, os.Stdout}
+	in.terminal = term.NewTerminal(rw, promptPrimary)
+	in.terminal.History = terminalHistory{history}
+	in.terminal.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != keyContinue {
+			return "", 0, false
+		}
+		in.continued = true
+		return line, pos, true
+	}
+	in.terminal.SetBracketedPasteMode(true)
+
+	return in, nil
+}
+
+// Close restores terminal settings changed by the Input
+func (in *Input) Close() {
+	if in.terminal != nil {
+		in.terminal.SetBracketedPasteMode(false)
+	}
+}
+
+// ReadMessage reads a complete, possibly multi-line, message.
+// It returns io.EOF when the user ends the session with Ctrl-D or Ctrl-C.
+func (in *Input) ReadMessage() (string, error) {
+	var lines []string
+	multiLine := false
+	prompt := promptPrimary
+
+	for {
+		line, more, err := in.readLine(prompt)
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case !multiLine && len(lines) == 0 && strings.TrimSpace(line) == multiLineDelimiter:
+			multiLine = true
+		case multiLine && strings.TrimSpace(line) == multiLineDelimiter:
+			return in.finish(lines), nil
+		case multiLine || more:
+			lines = append(lines, line)
+		default:
+			lines = append(lines, line)
+			return in.finish(lines), nil
+		}
+		prompt = promptContinuation
+	}
+}
+
+// ReadLine reads a single line, e.g. to answer a confirmation
+func (in *Input) ReadLine(prompt string) (string, error) {
+	line, _, err := in.readLine(prompt)
+	return strings.TrimSpace(line), err
+}
+
+// finish joins the lines of a message and records it in the history
+func (in *Input) finish(lines []string) string {
+	message := strings.TrimSpace(strings.Join(lines, "\n"))
+	in.history.Add(message)
+	return message
+}
+
+// readLine reads one line, reporting whether the message continues on the next one
+func (in *Input) readLine(prompt string) (string, bool, error) {
+	if in.terminal == nil {
+		fmt.Print(prompt)
+		line, err := in.reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", false, err
+		}
+		return strings.TrimRight(line, "\r\n"), false, nil
+	}
+
+	oldState, err := term.MakeRaw(in.fd)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to set terminal raw mode: %w", err)
+	}
+	defer term.Restore(in.fd, oldState)
+
+	if width, height, err := term.GetSize(in.fd); err == nil {
+		in.terminal.SetSize(width, height)
+	}
+	in.terminal.SetPrompt(prompt)
+
+	in.continued = false
+	line, err := in.terminal.ReadLine()
+	if errors.Is(err, term.ErrPasteIndicator) {
+		return line, true, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return line, in.continued, nil
+}
+
+// altEnterReader rewrites the Alt-Enter sequence (ESC followed by CR or LF)
+// into keyContinue followed by CR, which term.Terminal would otherwise drop
+type altEnterReader struct {
+	r io.Reader
+}
+
+func (a *altEnterReader) Read(p []byte) (int, error) {
+	n, err := a.r.Read(p)
+	for i := 0; i+1 < n; i++ {
+		if p[i] == '\x1b' && (p[i+1] == '\r' || p[i+1] == '\n') {
+			p[i] = keyContinue
+			p[i+1] = '\r'
+		}
+	}
+	return n, err
+}
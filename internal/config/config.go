@@ -2,8 +2,11 @@ package config
 
 import (
 	"fmt"
-	"os/exec"
+	"regexp"
 	"strings"
+
+	"github.com/y0ug/ai-helper/internal/io"
+	"github.com/y0ug/ai-helper/internal/secret"
 )
 
 const (
@@ -29,42 +32,266 @@ func (c *Config) GetCommandPrompt(name string) (string, string, error) {
 	return cmd.Prompt, cmd.System, nil
 }
 
-// ValidateConfig checks if the configuration is valid
-func (c *Config) ValidateConfig() error {
-	if len(c.Commands) == 0 {
-		return fmt.Errorf("no commands defined in configuration")
+// InputSpec returns the input sources declared by the command's Input variable, e.g.
+// "stdin|arg" gives ["stdin", "arg"]. An exec command without the exec type runs last.
+func (c Command) InputSpec() io.InputSpec {
+	for _, v := range c.Variables {
+		if v.Name == "Input" && v.Type != "" {
+			spec := io.InputSpec{Types: strings.Split(v.Type, "|"), Exec: v.Exec, Glob: v.Glob, MaxSize: v.MaxSize}
+			// An invalid duration is reported by validation and left to the default here
+			spec.ExecOptions, _ = v.ExecOptions()
+			if v.Exec != "" && !contains(spec.Types, "exec") {
+				spec.Types = append(spec.Types, "exec")
+			}
+			return spec
+		}
 	}
+	return io.InputSpec{}
+}
 
-	for name, cmd := range c.Commands {
-		if cmd.Prompt == "" {
-			return fmt.Errorf("empty prompt for command '%s'", name)
+// ReadsInput reports whether the command reads an input, false when input is not set
+func (c Command) ReadsInput() bool {
+	return c.Input != nil && *c.Input
+}
+
+// IsStrict reports whether the command fails on missing template keys
+func (c Command) IsStrict() bool {
+	return c.Strict != nil && *c.Strict
+}
+
+// SessionRules compiles the session redact rules for the secret scanner
+func (c *Config) SessionRules() []secret.Rule {
+	var rules []secret.Rule
+	for _, r := range c.Session.Redact {
+		if re, err := regexp.Compile(r.Pattern); err == nil {
+			rules = append(rules, secret.Rule{Name: r.Name, Pattern: re})
 		}
 	}
+	return rules
+}
 
+// OutputStepTypes lists the supported output step types
+var OutputStepTypes = []string{
+	"trim", "strip_fences", "strip_quotes", "extract_json", "validate_json", "regex", "wrap",
+}
+
+// Validate checks the step type and its options
+func (s OutputStep) Validate() error {
+	switch s.Type {
+	case "regex":
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil || s.Pattern == "" {
+			return fmt.Errorf("regex step needs a valid pattern: %q", s.Pattern)
+		}
+		if s.Group < 0 || s.Group > re.NumSubexp() {
+			return fmt.Errorf("regex group %d out of range, pattern has %d", s.Group, re.NumSubexp())
+		}
+	case "wrap":
+		if s.Width < 0 {
+			return fmt.Errorf("wrap width must be positive")
+		}
+	default:
+		for _, t := range OutputStepTypes {
+			if s.Type == t {
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown step type %q (valid: %s)", s.Type, strings.Join(OutputStepTypes, ", "))
+	}
 	return nil
 }
 
-// LoadPromptContent loads the prompt content, system prompt, and processes any variables
-func LoadPromptContent(cmd Command) (string, string, map[string]interface{}, error) {
-	vars := make(map[string]interface{})
+// Pattern returns the pattern of the guard and whether output matching it is rejected,
+// rather than required. Reject wins when both are set, which Validate refuses.
+func (g Guard) Pattern() (pattern string, reject bool) {
+	if g.Reject != "" {
+		return g.Reject, true
+	}
+	return g.Require, false
+}
 
-	// Process any variables defined in the command
+// Validate checks that the guard has exactly one valid pattern
+func (g Guard) Validate() error {
+	if (g.Require == "") == (g.Reject == "") {
+		return fmt.Errorf("guard needs either require or reject")
+	}
+	pattern, _ := g.Pattern()
+	if _, err := regexp.Compile(pattern); err != nil {
+		return fmt.Errorf("invalid guard pattern: %w", err)
+	}
+	return nil
+}
+
+// Validate checks that exactly one condition of the check is set
+func (p PreCheck) Validate() error {
+	set := 0
+	if strings.TrimSpace(p.Run) != "" {
+		set++
+	}
+	if p.NotEmpty {
+		set++
+	}
+	if p.MaxBytes != 0 {
+		set++
+	}
+	if set != 1 {
+		return fmt.Errorf("pre check needs exactly one of run, not_empty or max_bytes")
+	}
+	if p.MaxBytes < 0 {
+		return fmt.Errorf("max_bytes must be positive")
+	}
+	return nil
+}
+
+var envRefRe = regexp.MustCompile(`\.Env\.([A-Za-z_][A-Za-z0-9_]*)|index\s+\.Env\s+"([^"]+)"`)
+
+// EnvNames returns the patterns of the environment variables exposed to the command's
+// templates: its env list when set, otherwise the variables its templates reference
+// as .Env.NAME or index .Env "NAME"
+func (c Command) EnvNames() []string {
+	if len(c.Env) > 0 {
+		return c.Env
+	}
+	var names []string
+	for _, m := range envRefRe.FindAllStringSubmatch(c.Partials+"\n"+c.System+"\n"+c.Prompt, -1) {
+		names = append(names, m[1]+m[2])
+	}
+	return names
+}
+
+// extendsChain returns the commands that name extends, nearest first, failing on an
+// unknown base or a cycle
+func (c *Config) extendsChain(name string) ([]string, error) {
+	path := []string{name}
+	var chain []string
+	for cmd := c.Commands[name]; cmd.Extends != ""; {
+		base := cmd.Extends
+		for _, seen := range path {
+			if seen == base {
+				return nil, fmt.Errorf("extends cycle: %s", strings.Join(append(path, base), " -> "))
+			}
+		}
+		next, ok := c.Commands[base]
+		if !ok {
+			return nil, fmt.Errorf("command '%s' extends unknown command '%s'", path[len(path)-1], base)
+		}
+		path = append(path, base)
+		chain = append(chain, base)
+		cmd = next
+	}
+	return chain, nil
+}
+
+// ResolveExtends replaces every command extending another with the result of
+// inheriting from its bases, from the farthest to the nearest. Commands with an
+// unknown base or an extends cycle are left as is, ValidateConfig reports them.
+func (c *Config) ResolveExtends() {
+	resolved := make(map[string]Command, len(c.Commands))
+	for name, cmd := range c.Commands {
+		chain, err := c.extendsChain(name)
+		if err == nil && len(chain) > 0 {
+			base := c.Commands[chain[len(chain)-1]]
+			for i := len(chain) - 2; i >= 0; i-- {
+				base = c.Commands[chain[i]].inherit(base)
+			}
+			cmd = cmd.inherit(base)
+		}
+		resolved[name] = cmd
+	}
+	c.Commands = resolved
+}
+
+// inherit returns the command completed with the fields of base it does not set.
+// Variables, params and parameters are merged, those of the command winning.
+func (cmd Command) inherit(base Command) Command {
+	if cmd.Description == "" {
+		cmd.Description = base.Description
+	}
+	if cmd.System == "" {
+		cmd.System, cmd.SystemFile = base.System, base.SystemFile
+	}
+	if cmd.Prompt == "" {
+		cmd.Prompt, cmd.PromptFile = base.Prompt, base.PromptFile
+	}
+	if cmd.Input == nil {
+		cmd.Input = base.Input
+	}
+	if cmd.Strict == nil {
+		cmd.Strict = base.Strict
+	}
+	if cmd.InputCommand == "" {
+		cmd.InputCommand = base.InputCommand
+	}
+	if cmd.Files == nil {
+		cmd.Files = base.Files
+	}
+	if cmd.Output == nil {
+		cmd.Output = base.Output
+	}
+	if cmd.ErrorPattern == "" {
+		cmd.ErrorPattern = base.ErrorPattern
+	}
+	if cmd.Guards == nil {
+		cmd.Guards = base.Guards
+	}
+	if cmd.Post == nil {
+		cmd.Post = base.Post
+	}
+	if cmd.Pre == nil {
+		cmd.Pre = base.Pre
+	}
+	if cmd.Secrets == "" {
+		cmd.Secrets = base.Secrets
+	}
+	if cmd.Env == nil {
+		cmd.Env = base.Env
+	}
+	if cmd.Model == "" {
+		cmd.Model = base.Model
+	}
+
+	variables := append([]Variable{}, base.Variables...)
 	for _, v := range cmd.Variables {
-		if v.Name == "Input" {
-			continue
+		replaced := false
+		for i := range variables {
+			if variables[i].Name == v.Name {
+				variables[i], replaced = v, true
+			}
+		}
+		if !replaced {
+			variables = append(variables, v)
 		}
-		if v.Type == "exec" && v.Exec != "" && v.Name != "" {
-			// Execute command and capture output
-			out, err := exec.Command("sh", "-c", v.Exec).Output()
-			if err != nil {
-				return "", "", nil, fmt.Errorf("error executing command %s: %w", v.Exec, err)
+	}
+	if len(variables) > 0 {
+		cmd.Variables = variables
+	}
+
+	params := append([]Param{}, base.Params...)
+	for _, p := range cmd.Params {
+		replaced := false
+		for i := range params {
+			if params[i].Name == p.Name {
+				params[i], replaced = p, true
 			}
-			vars[v.Name] = strings.TrimSpace(string(out))
-		} else if v.Name != "" {
-			// Store regular variable name for later use
-			vars[v.Name] = ""
 		}
+		if !replaced {
+			params = append(params, p)
+		}
+	}
+	if len(params) > 0 {
+		cmd.Params = params
+	}
+
+	if len(base.Parameters) > 0 {
+		parameters := make(map[string]interface{}, len(base.Parameters)+len(cmd.Parameters))
+		for k, v := range base.Parameters {
+			parameters[k] = v
+		}
+		for k, v := range cmd.Parameters {
+			parameters[k] = v
+		}
+		cmd.Parameters = parameters
 	}
 
-	return cmd.Prompt, cmd.System, vars, nil
+	return cmd
 }
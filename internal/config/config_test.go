@@ -0,0 +1,128 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestConfigResolveExtends(t *testing.T) {
+	cfg := &Config{Commands: map[string]Command{
+		"base": {
+			System:     "shared system",
+			Prompt:     "base prompt",
+			Variables:  []Variable{{Name: "Input", Type: "stdin"}, {Name: "Lang", Type: "exec"}},
+			Files:      []string{"a.md"},
+			Model:      "openai/gpt-4o",
+			Parameters: map[string]interface{}{"temperature": 0.2, "max_tokens": 500},
+			Output:     []OutputStep{{Type: "trim"}},
+			Input:      boolPtr(true),
+			Strict:     boolPtr(true),
+		},
+		"middle": {
+			Extends:    "base",
+			Prompt:     "middle prompt",
+			Parameters: map[string]interface{}{"temperature": 0.7},
+		},
+		"leaf": {
+			Extends:   "middle",
+			Variables: []Variable{{Name: "Input", Type: "arg"}, {Name: "Extra", Type: "exec"}},
+			Model:     "anthropic/claude",
+			Strict:    boolPtr(false),
+		},
+	}}
+
+	cfg.ResolveExtends()
+
+	leaf := cfg.Commands["leaf"]
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"system from base", leaf.System, "shared system"},
+		{"prompt from middle", leaf.Prompt, "middle prompt"},
+		{"own model", leaf.Model, "anthropic/claude"},
+		{"files from base", strings.Join(leaf.Files, ","), "a.md"},
+		{"output from base", len(leaf.Output), 1},
+		{"temperature from middle", leaf.Parameters["temperature"], 0.7},
+		{"max_tokens from base", leaf.Parameters["max_tokens"], 500},
+		{"variables merged", len(leaf.Variables), 3},
+		{"variable overridden", leaf.Variables[0].Type, "arg"},
+		{"base untouched", cfg.Commands["base"].Parameters["temperature"], 0.2},
+		{"middle model", cfg.Commands["middle"].Model, "openai/gpt-4o"},
+		{"input from base", leaf.ReadsInput(), true},
+		{"strict turned off", leaf.IsStrict(), false},
+		{"middle strict from base", cfg.Commands["middle"].IsStrict(), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigValidateExtends(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands map[string]Command
+		want     string
+	}{
+		{
+			"cycle",
+			map[string]Command{
+				"a": {Extends: "b", Prompt: "x"},
+				"b": {Extends: "c", Prompt: "x"},
+				"c": {Extends: "a", Prompt: "x"},
+			},
+			"extends cycle: ",
+		},
+		{"self", map[string]Command{"a": {Extends: "a", Prompt: "x"}}, "extends cycle: a -> a"},
+		{"unknown", map[string]Command{"a": {Extends: "missing", Prompt: "x"}}, "command 'a' extends unknown command 'missing'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Commands: tt.commands}
+			err := cfg.ValidateConfig()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ValidateConfig() error = %v, want it to contain %q", err, tt.want)
+			}
+			cfg.ResolveExtends()
+			if cfg.Commands["a"].Prompt != "x" {
+				t.Errorf("ResolveExtends() changed a broken command: %+v", cfg.Commands["a"])
+			}
+		})
+	}
+}
+
+func TestGuardPattern(t *testing.T) {
+	tests := []struct {
+		name        string
+		guard       Guard
+		wantPattern string
+		wantReject  bool
+		wantErr     bool
+	}{
+		{"require", Guard{Require: "^feat"}, "^feat", false, false},
+		{"reject", Guard{Reject: "PRIVATE KEY"}, "PRIVATE KEY", true, false},
+		{"both", Guard{Require: "a", Reject: "b"}, "b", true, true},
+		{"none", Guard{}, "", false, true},
+		{"invalid", Guard{Reject: "("}, "(", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern, reject := tt.guard.Pattern()
+			if pattern != tt.wantPattern || reject != tt.wantReject {
+				t.Errorf("Pattern() = %q, %v, want %q, %v", pattern, reject, tt.wantPattern, tt.wantReject)
+			}
+			if err := tt.guard.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
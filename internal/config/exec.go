@@ -0,0 +1,85 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/y0ug/ai-helper/internal/io"
+	"github.com/y0ug/ai-helper/internal/secret"
+)
+
+// ExecOptions returns how the exec command of the variable runs. Outputs holding secrets
+// are never cached.
+func (v Variable) ExecOptions() (io.ExecOptions, error) {
+	opts := io.ExecOptions{Dir: v.Dir, Env: v.Env, Cacheable: noSecrets}
+	var err error
+	if v.Timeout != "" {
+		if opts.Timeout, err = parseDuration(v.Timeout); err != nil {
+			return opts, fmt.Errorf("invalid timeout of variable %s: %w", v.Name, err)
+		}
+	}
+	if v.Cache != "" {
+		if opts.CacheTTL, err = parseDuration(v.Cache); err != nil {
+			return opts, fmt.Errorf("invalid cache of variable %s: %w", v.Name, err)
+		}
+	}
+	return opts, nil
+}
+
+// noSecrets reports whether the secret scanner finds nothing in output
+func noSecrets(output string) bool {
+	return len(secret.Scan(output)) == 0
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s is not a positive duration", s)
+	}
+	return d, nil
+}
+
+// ExecVariables runs the commands of the exec variables of vars, Input aside, at the
+// same time and returns their trimmed outputs by name. The error lists every variable
+// that failed, in declaration order.
+func ExecVariables(vars []Variable) (map[string]interface{}, error) {
+	var execVars []Variable
+	for _, v := range vars {
+		if v.Name != "Input" && v.Type == "exec" && v.Exec != "" {
+			execVars = append(execVars, v)
+		}
+	}
+
+	outputs := make([]string, len(execVars))
+	errs := make([]error, len(execVars))
+	var wg sync.WaitGroup
+	for i, v := range execVars {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			opts, err := v.ExecOptions()
+			if err == nil {
+				outputs[i], err = io.RunCommand(v.Exec, opts)
+			}
+			if err != nil {
+				errs[i] = fmt.Errorf("variable %s: %w", v.Name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{}, len(execVars))
+	for i, v := range execVars {
+		values[v.Name] = strings.TrimSpace(outputs[i])
+	}
+	return values, nil
+}
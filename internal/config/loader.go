@@ -1,8 +1,11 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,6 +13,9 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// CommandsDir is the directory, next to a config file, holding one command per file
+const CommandsDir = "commands.d"
+
 // Loader handles configuration file loading
 type Loader struct{}
 
@@ -20,30 +26,258 @@ func NewLoader() *Loader {
 
 // Load reads and parses the configuration file
 func (l *Loader) Load(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
+	return l.LoadFiles([]string{path})
+}
+
+// LoadFiles reads the configuration files and merges them, later files taking
+// precedence. A command defined in several files is replaced as a whole by its last
+// definition; the session redact rules and the templates of all files are combined.
+// Commands are then resolved against the command they extend, so a project command
+// can extend a global one.
+//
+// Each file is expanded first: the commands of the commands.d directory next to it,
+// then the files it includes, then its own commands, each overriding the previous.
+func (l *Loader) LoadFiles(paths []string) (*Config, error) {
+	merged, err := l.Read(paths)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, err
+	}
+
+	if err := merged.ValidateConfig(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return merged, nil
+}
+
+// Read is LoadFiles without the validation, for reporting every problem of the merged
+// configuration with Problems
+func (l *Loader) Read(paths []string) (*Config, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no config file found")
+	}
+
+	merged := newConfig()
+	seenDirs := make(map[string]bool)
+	for _, path := range paths {
+		config, err := l.loadFile(path, nil)
+		if err != nil {
+			return nil, err
+		}
+		if dir := filepath.Join(filepath.Dir(path), CommandsDir); !seenDirs[dir] {
+			seenDirs[dir] = true
+			commands, err := l.loadCommandsDir(dir)
+			if err != nil {
+				return nil, err
+			}
+			merged.merge(commands)
+		}
+		merged.merge(config)
+	}
+
+	merged.ResolveExtends()
+
+	partials := strings.Join(merged.partials, "\n")
+	for name, cmd := range merged.Commands {
+		cmd.Partials = partials
+		merged.Commands[name] = cmd
 	}
 
+	return merged, nil
+}
+
+// loadFile parses a configuration file and the files it includes, recording the file
+// defining each command. stack holds the files being included, to detect cycles.
+func (l *Loader) loadFile(path string, stack []string) (*Config, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config file %s: %w", path, err)
+	}
+	for i, p := range stack {
+		if p == absPath {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack[i:], absPath), " -> "))
+		}
+	}
+	stack = append(stack[:len(stack):len(stack)], absPath)
+
 	var config Config
+	if err := decodeFile(path, &config); err != nil {
+		return nil, err
+	}
+
+	merged := newConfig()
+	for _, pattern := range config.Include {
+		includes, err := resolveInclude(path, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, include := range includes {
+			included, err := l.loadFile(include, stack)
+			if err != nil {
+				return nil, err
+			}
+			merged.merge(included)
+		}
+	}
+
+	for _, pattern := range config.Templates {
+		files, err := resolveInclude(path, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read template file: %w", err)
+			}
+			config.partials = append(config.partials, string(content))
+		}
+	}
+
+	for name, cmd := range config.Commands {
+		cmd.Source = path
+		if is := cmd.readTemplateFiles(); is != nil {
+			return nil, newLocator().problem(cmd, name, *is)
+		}
+		config.Commands[name] = cmd
+	}
+	config.Sources = []string{path}
+	merged.merge(&config)
+
+	return merged, nil
+}
+
+// loadCommandsDir loads the commands of dir, each file defining the command named
+// after it, e.g. commands.d/review.yaml defines review. A missing dir is not an error.
+func (l *Loader) loadCommandsDir(dir string) (*Config, error) {
+	config := newConfig()
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commands directory %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		var cmd Command
+		if err := decodeFile(path, &cmd); err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		cmd.Source = path
+		if is := cmd.readTemplateFiles(); is != nil {
+			return nil, newLocator().problem(cmd, name, *is)
+		}
+		config.Commands[name] = cmd
+		config.Sources = append(config.Sources, path)
+	}
+
+	return config, nil
+}
+
+// readTemplateFiles reads the prompt_file and system_file of a command, relative to the
+// directory of its source, into Prompt and System. The paths are made absolute.
+func (cmd *Command) readTemplateFiles() *issue {
+	for _, f := range []struct {
+		name string
+		path *string
+		text *string
+		key  string
+	}{
+		{"prompt_file", &cmd.PromptFile, &cmd.Prompt, "prompt"},
+		{"system_file", &cmd.SystemFile, &cmd.System, "system"},
+	} {
+		if *f.path == "" {
+			continue
+		}
+		if *f.text != "" {
+			return &issue{path: []string{f.name}, message: fmt.Sprintf("%s and %s are mutually exclusive", f.key, f.name)}
+		}
+		if !filepath.IsAbs(*f.path) {
+			*f.path = filepath.Join(filepath.Dir(cmd.Source), *f.path)
+		}
+		content, err := os.ReadFile(*f.path)
+		if err != nil {
+			return &issue{path: []string{f.name}, message: fmt.Sprintf("failed to read %s: %v", f.name, err)}
+		}
+		*f.text = string(content)
+	}
+	return nil
+}
+
+// resolveInclude returns the files matching an include or templates pattern, relative to the
+// directory of the including file. A pattern without wildcards must name a file.
+func resolveInclude(from, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(from), pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include %q in %s: %w", pattern, from, err)
+	}
+	if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+		return nil, fmt.Errorf("%s not found, referenced from %s", pattern, from)
+	}
+	return matches, nil
+}
+
+// decodeFile reads a YAML or JSON file into v
+func decodeFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// Unknown keys are rejected, they are most often typos of a known one
 	ext := strings.ToLower(filepath.Ext(path))
-	
 	switch ext {
 	case ".yaml", ".yml":
-		err = yaml.Unmarshal(data, &config)
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err = dec.Decode(v); err == io.EOF {
+			err = nil
+		}
 	case ".json":
-		err = json.Unmarshal(data, &config)
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(v)
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line := bytes.Count(data[:syntaxErr.Offset], []byte("\n")) + 1
+			return fmt.Errorf("failed to parse config file %s:%d: %w", path, line, err)
+		}
 	default:
-		return nil, fmt.Errorf("unsupported config file format: %s", ext)
+		return fmt.Errorf("unsupported config file format: %s", ext)
 	}
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
 	}
+	return nil
+}
 
-	if err := config.ValidateConfig(); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
-	}
+func newConfig() *Config {
+	return &Config{Commands: make(map[string]Command)}
+}
 
-	return &config, nil
+// merge adds the commands and settings of other on top of c
+func (c *Config) merge(other *Config) {
+	for name, cmd := range other.Commands {
+		if prev, ok := c.Commands[name]; ok {
+			cmd.Overrides = append(append([]string{}, prev.Overrides...), prev.Source)
+		}
+		c.Commands[name] = cmd
+	}
+	c.Session.Redact = append(c.Session.Redact, other.Session.Redact...)
+	c.Sources = append(c.Sources, other.Sources...)
+	c.partials = append(c.partials, other.partials...)
 }
@@ -0,0 +1,292 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoaderLoadFiles(t *testing.T) {
+	dir := t.TempDir()
+	global := writeConfig(t, dir, "global.yaml", `
+commands:
+  ask:
+    prompt: global ask
+    files: [notes.md]
+  commit:
+    prompt: global commit
+session:
+  redact:
+    - name: ticket
+      pattern: 'TCK-\d+'
+`)
+	project := writeConfig(t, dir, "project.json", `{"commands": {"commit": {"prompt": "project commit"}}}`)
+	explicit := writeConfig(t, dir, "explicit.yaml", `
+commands:
+  commit:
+    prompt: explicit commit
+  review:
+    prompt: review
+session:
+  redact:
+    - name: host
+      pattern: 'srv-\d+'
+`)
+
+	cfg, err := NewLoader().LoadFiles([]string{global, project, explicit})
+	if err != nil {
+		t.Fatalf("LoadFiles() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		prompt    string
+		files     int
+		source    string
+		overrides []string
+	}{
+		{"ask", "global ask", 1, global, nil},
+		{"commit", "explicit commit", 0, explicit, []string{global, project}},
+		{"review", "review", 0, explicit, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, ok := cfg.Commands[tt.name]
+			if !ok {
+				t.Fatalf("command %s missing", tt.name)
+			}
+			if cmd.Prompt != tt.prompt || len(cmd.Files) != tt.files {
+				t.Errorf("command = %+v, want prompt %q with %d files", cmd, tt.prompt, tt.files)
+			}
+			if cmd.Source != tt.source {
+				t.Errorf("Source = %s, want %s", cmd.Source, tt.source)
+			}
+			if strings.Join(cmd.Overrides, ",") != strings.Join(tt.overrides, ",") {
+				t.Errorf("Overrides = %v, want %v", cmd.Overrides, tt.overrides)
+			}
+		})
+	}
+
+	if len(cfg.Session.Redact) != 2 {
+		t.Errorf("Session.Redact = %+v, want the rules of both files", cfg.Session.Redact)
+	}
+	if strings.Join(cfg.Sources, ",") != strings.Join([]string{global, project, explicit}, ",") {
+		t.Errorf("Sources = %v", cfg.Sources)
+	}
+}
+
+func TestLoaderLoadFilesErrors(t *testing.T) {
+	dir := t.TempDir()
+	valid := writeConfig(t, dir, "valid.yaml", "commands:\n  ask:\n    prompt: x\n")
+	broken := writeConfig(t, dir, "broken.yaml", "commands: [\n")
+
+	tests := []struct {
+		name  string
+		paths []string
+		want  string
+	}{
+		{"none", nil, "no config file found"},
+		{"parse error names the file", []string{valid, broken}, broken},
+		{"missing file", []string{filepath.Join(dir, "missing.yaml")}, "failed to read config file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLoader().LoadFiles(tt.paths)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadFiles() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoaderIncludes(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{"prompts", CommandsDir} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	top := writeConfig(t, dir, "ai-helper.yaml", `
+include:
+  - prompts/*.yaml
+commands:
+  ask:
+    prompt: main ask
+`)
+	shared := writeConfig(t, dir, "prompts/shared.yaml", "commands:\n  ask:\n    prompt: shared ask\n  explain:\n    prompt: shared explain\n")
+	review := writeConfig(t, dir, CommandsDir+"/review.yaml", "description: Review code\nprompt: review {{ .Input }}\n")
+	fix := writeConfig(t, dir, CommandsDir+"/fix.json", `{"prompt": "fix"}`)
+	writeConfig(t, dir, CommandsDir+"/README.md", "ignored")
+
+	cfg, err := NewLoader().Load(top)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		prompt string
+		source string
+	}{
+		{"ask", "main ask", top},
+		{"explain", "shared explain", shared},
+		{"review", "review {{ .Input }}", review},
+		{"fix", "fix", fix},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := cfg.Commands[tt.name]
+			if cmd.Prompt != tt.prompt || cmd.Source != tt.source {
+				t.Errorf("command = %q from %s, want %q from %s", cmd.Prompt, cmd.Source, tt.prompt, tt.source)
+			}
+		})
+	}
+	if len(cfg.Commands) != len(tests) {
+		t.Errorf("got %d commands, want %d", len(cfg.Commands), len(tests))
+	}
+	if got := cfg.Commands["ask"].Overrides; len(got) != 1 || got[0] != shared {
+		t.Errorf("ask Overrides = %v, want [%s]", got, shared)
+	}
+}
+
+func TestLoaderIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			"cycle",
+			map[string]string{
+				"ai-helper.yaml": "include: [a.yaml]\ncommands:\n  ask:\n    prompt: x\n",
+				"a.yaml":         "include: [b.yaml]\n",
+				"b.yaml":         "include: [a.yaml]\n",
+			},
+			"include cycle: ",
+		},
+		{
+			"missing include",
+			map[string]string{"ai-helper.yaml": "include: [missing.yaml]\n"},
+			"missing.yaml not found, referenced from",
+		},
+		{
+			"parse error in include",
+			map[string]string{"ai-helper.yaml": "include: [bad.yaml]\n", "bad.yaml": "commands: [\n"},
+			"bad.yaml",
+		},
+		{
+			"invalid command file",
+			map[string]string{"ai-helper.yaml": "commands:\n  ask:\n    prompt: x\n", CommandsDir + "/empty.yaml": "description: no prompt\n"},
+			"empty.yaml:1:1: command 'empty': empty prompt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(dir, CommandsDir), 0755); err != nil {
+				t.Fatal(err)
+			}
+			for name, content := range tt.files {
+				writeConfig(t, dir, name, content)
+			}
+			_, err := NewLoader().Load(filepath.Join(dir, "ai-helper.yaml"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoaderTemplateFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{"prompts", "partials", CommandsDir} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	top := writeConfig(t, dir, "ai-helper.yaml", `
+templates: [partials/*.tmpl]
+commands:
+  review:
+    system_file: prompts/system.md
+    prompt_file: prompts/review.tmpl
+`)
+	writeConfig(t, dir, "prompts/system.md", "You review code.\n")
+	writeConfig(t, dir, "prompts/review.tmpl", `{{ template "rules" }} {{ .Input }}`)
+	writeConfig(t, dir, "partials/rules.tmpl", `{{ define "rules" }}Be brief, {{ .Env.USER }}.{{ end }}`)
+	writeConfig(t, dir, CommandsDir+"/fix.yaml", "prompt_file: ../prompts/review.tmpl\n")
+
+	cfg, err := NewLoader().Load(top)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	review := cfg.Commands["review"]
+	if review.System != "You review code.\n" || review.Prompt != `{{ template "rules" }} {{ .Input }}` {
+		t.Errorf("review = system %q, prompt %q", review.System, review.Prompt)
+	}
+	if want := filepath.Join(dir, "prompts", "review.tmpl"); review.PromptFile != want {
+		t.Errorf("PromptFile = %s, want %s", review.PromptFile, want)
+	}
+	if cfg.Commands["fix"].Prompt != review.Prompt {
+		t.Errorf("fix prompt = %q, want the file relative to commands.d", cfg.Commands["fix"].Prompt)
+	}
+	for name, cmd := range cfg.Commands {
+		if !strings.Contains(cmd.Partials, `define "rules"`) {
+			t.Errorf("%s Partials = %q, want the rules partial", name, cmd.Partials)
+		}
+	}
+	if got := review.EnvNames(); len(got) != 1 || got[0] != "USER" {
+		t.Errorf("EnvNames() = %v, want the variables of the partials", got)
+	}
+}
+
+func TestLoaderTemplateFileErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{"exclusive", "commands:\n  ask:\n    prompt: x\n    prompt_file: ask.md\n", "prompt and prompt_file are mutually exclusive"},
+		{"missing", "commands:\n  ask:\n    prompt_file: missing.md\n", "ai-helper.yaml:3:18: command 'ask': failed to read prompt_file"},
+		{"missing template", "templates: [partials.tmpl]\ncommands:\n  ask:\n    prompt: x\n", "partials.tmpl not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := writeConfig(t, dir, "ai-helper.yaml", tt.config)
+			_, err := NewLoader().Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoaderExtendsAcrossFiles(t *testing.T) {
+	dir := t.TempDir()
+	global := writeConfig(t, dir, "global.yaml", "commands:\n  commit:\n    system: Write commit messages\n    prompt: '{{ .Input }}'\n    parameters:\n      temperature: 0.2\n")
+	project := writeConfig(t, dir, "project.yaml", "commands:\n  commit-fr:\n    extends: commit\n    system: Write commit messages in French\n")
+
+	cfg, err := NewLoader().LoadFiles([]string{global, project})
+	if err != nil {
+		t.Fatalf("LoadFiles() error = %v", err)
+	}
+	cmd := cfg.Commands["commit-fr"]
+	if cmd.Prompt != "{{ .Input }}" || cmd.System != "Write commit messages in French" || cmd.Parameters["temperature"] != 0.2 {
+		t.Errorf("commit-fr = %+v, want the prompt and parameters of commit", cmd)
+	}
+	if cmd.Source != project {
+		t.Errorf("Source = %s, want %s", cmd.Source, project)
+	}
+}
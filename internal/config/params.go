@@ -0,0 +1,223 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/y0ug/ai-helper/internal/prompt"
+)
+
+// ParamTypes lists the supported parameter types
+var ParamTypes = []string{"string", "int", "bool", "enum"}
+
+// Param is a named command line parameter of a command, passed as --param name=value
+// or as an argument, and available to templates as .name and .Vars.name
+type Param struct {
+	Name        string      `yaml:"name"                  json:"name"`
+	Type        string      `yaml:"type,omitempty"        json:"type,omitempty"`   // string when empty
+	Values      []string    `yaml:"values,omitempty"      json:"values,omitempty"` // allowed values of an enum
+	Default     interface{} `yaml:"default,omitempty"     json:"default,omitempty"`
+	Required    bool        `yaml:"required,omitempty"    json:"required,omitempty"`
+	Description string      `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+// Validate checks the name, type and default of a parameter
+func (p Param) Validate() error {
+	if !identRe.MatchString(p.Name) {
+		return fmt.Errorf("parameter name %q is not a valid identifier", p.Name)
+	}
+	if contains(prompt.BuiltinFields, p.Name) {
+		return fmt.Errorf("parameter name %s is reserved for the template data", p.Name)
+	}
+	if p.Type != "" && !contains(ParamTypes, p.Type) {
+		return fmt.Errorf("unknown type %q for parameter %s (valid: %s)", p.Type, p.Name, strings.Join(ParamTypes, ", "))
+	}
+	if (p.Type == "enum") != (len(p.Values) > 0) {
+		return fmt.Errorf("parameter %s: values are required for, and only allowed on, enum parameters", p.Name)
+	}
+	if p.Default != nil {
+		if _, err := p.Parse(fmt.Sprint(p.Default)); err != nil {
+			return fmt.Errorf("invalid default: %w", err)
+		}
+	}
+	return nil
+}
+
+// Parse converts a command line value to the type of the parameter
+func (p Param) Parse(value string) (interface{}, error) {
+	switch p.Type {
+	case "int":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for parameter %s, expected an integer", value, p.Name)
+		}
+		return n, nil
+	case "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for parameter %s, expected true or false", value, p.Name)
+		}
+		return b, nil
+	case "enum":
+		if !contains(p.Values, value) {
+			return nil, fmt.Errorf("invalid value %q for parameter %s (valid: %s)", value, p.Name, strings.Join(p.Values, ", "))
+		}
+		return value, nil
+	default:
+		return value, nil
+	}
+}
+
+// zero returns the value of an optional parameter without default that is not passed
+func (p Param) zero() interface{} {
+	switch p.Type {
+	case "int":
+		return 0
+	case "bool":
+		return false
+	default:
+		return ""
+	}
+}
+
+// Usage describes how to pass the parameter, e.g. lang=<go|python>
+func (p Param) Usage() string {
+	switch p.Type {
+	case "enum":
+		return fmt.Sprintf("%s=<%s>", p.Name, strings.Join(p.Values, "|"))
+	case "":
+		return fmt.Sprintf("%s=<string>", p.Name)
+	default:
+		return fmt.Sprintf("%s=<%s>", p.Name, p.Type)
+	}
+}
+
+// param returns the parameter declared with name, or nil
+func (c Command) param(name string) *Param {
+	for i := range c.Params {
+		if c.Params[i].Name == name {
+			return &c.Params[i]
+		}
+	}
+	return nil
+}
+
+// askVariable returns the ask variable declared with name, or nil
+func (c Command) askVariable(name string) *Variable {
+	for i := range c.Variables {
+		if c.Variables[i].Name == name && c.Variables[i].Type == "ask" {
+			return &c.Variables[i]
+		}
+	}
+	return nil
+}
+
+// ParseParams resolves the parameters of the command from the --param flags, given as
+// name=value, and the command line arguments. Arguments of the form name=value naming a
+// parameter are taken as parameters; when the command does not read its input from the
+// arguments, the other arguments fill the unset parameters in order. It returns the
+// typed values, defaults applied, and the arguments left for the input.
+//
+// Ask variables can be answered the same way, by name only. Their answers are part of
+// the values, the variables not answered are not.
+func (c Command) ParseParams(flags, args []string) (map[string]interface{}, []string, error) {
+	values := make(map[string]interface{}, len(c.Params))
+	raw := make(map[string]string)
+	set := func(name, value string, hasValue bool) error {
+		if v := c.askVariable(name); v != nil {
+			if !hasValue {
+				value = "true"
+			}
+			answer, err := v.ParseAnswer(value)
+			values[name] = answer
+			return err
+		}
+		p := c.param(name)
+		if p == nil {
+			return fmt.Errorf("unknown parameter %q%s", name, c.availableParams())
+		}
+		if !hasValue {
+			if p.Type != "bool" {
+				return fmt.Errorf("parameter %s needs a value: %s", name, p.Usage())
+			}
+			value = "true"
+		}
+		raw[name] = value
+		return nil
+	}
+
+	for _, flag := range flags {
+		name, value, hasValue := strings.Cut(flag, "=")
+		if err := set(name, value, hasValue); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var rest []string
+	for _, arg := range args {
+		if name, value, ok := strings.Cut(arg, "="); ok && (c.param(name) != nil || c.askVariable(name) != nil) {
+			if err := set(name, value, true); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		rest = append(rest, arg)
+	}
+
+	if len(c.Params) > 0 && !c.argInput() {
+		for _, p := range c.Params {
+			if _, ok := raw[p.Name]; !ok && len(rest) > 0 {
+				raw[p.Name], rest = rest[0], rest[1:]
+			}
+		}
+		if len(rest) > 0 {
+			return nil, nil, fmt.Errorf("too many arguments: %s", strings.Join(rest, " "))
+		}
+	}
+
+	for _, p := range c.Params {
+		value, ok := raw[p.Name]
+		switch {
+		case ok:
+		case p.Default != nil:
+			value = fmt.Sprint(p.Default)
+		case p.Required:
+			return nil, nil, fmt.Errorf("missing required parameter %s, pass --param %s", p.Name, p.Usage())
+		default:
+			values[p.Name] = p.zero()
+			continue
+		}
+
+		v, err := p.Parse(value)
+		if err != nil {
+			return nil, nil, err
+		}
+		values[p.Name] = v
+	}
+
+	return values, rest, nil
+}
+
+// argInput reports whether the command reads its input, or the files of it, from the
+// arguments
+func (c Command) argInput() bool {
+	types := c.InputSpec().Types
+	return c.ReadsInput() && (contains(types, "arg") || contains(types, "file"))
+}
+
+func (c Command) availableParams() string {
+	var names []string
+	for _, p := range c.Params {
+		names = append(names, p.Name)
+	}
+	for _, v := range c.Variables {
+		if v.Type == "ask" {
+			names = append(names, v.Name)
+		}
+	}
+	if len(names) == 0 {
+		return ", the command has no parameters"
+	}
+	return fmt.Sprintf(" (available: %s)", strings.Join(names, ", "))
+}
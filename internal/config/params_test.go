@@ -0,0 +1,132 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParamValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		param   Param
+		wantErr string
+	}{
+		{"string", Param{Name: "lang"}, ""},
+		{"int default", Param{Name: "count", Type: "int", Default: 3}, ""},
+		{"enum", Param{Name: "tone", Type: "enum", Values: []string{"formal", "casual"}, Default: "casual"}, ""},
+		{"bad name", Param{Name: "max-len"}, "not a valid identifier"},
+		{"reserved name", Param{Name: "Input"}, "reserved for the template data"},
+		{"reserved vars", Param{Name: "Vars"}, "reserved for the template data"},
+		{"bad type", Param{Name: "lang", Type: "float"}, `unknown type "float"`},
+		{"enum without values", Param{Name: "tone", Type: "enum"}, "values are required"},
+		{"values without enum", Param{Name: "tone", Values: []string{"a"}}, "values are required"},
+		{"bad default", Param{Name: "count", Type: "int", Default: "many"}, "invalid default"},
+		{"default not in enum", Param{Name: "tone", Type: "enum", Values: []string{"a"}, Default: "b"}, "invalid default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.param.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCommandParseParams(t *testing.T) {
+	var withParams Command
+	if err := yaml.Unmarshal([]byte(`
+prompt: x
+params:
+  - name: lang
+    type: enum
+    values: [go, python]
+    required: true
+  - name: count
+    type: int
+    default: 3
+  - name: verbose
+    type: bool
+`), &withParams); err != nil {
+		t.Fatal(err)
+	}
+	withInput := withParams
+	withInput.Input = boolPtr(true)
+	withInput.Variables = []Variable{{Name: "Input", Type: "stdin|arg"}}
+
+	withQuestions := Command{Prompt: "x", Variables: []Variable{
+		{Name: "Title", Type: "ask"},
+		{Name: "Urgent", Type: "ask", Ask: "confirm"},
+		{Name: "Branch", Type: "exec", Exec: "git branch"},
+	}}
+
+	tests := []struct {
+		name     string
+		cmd      Command
+		flags    []string
+		args     []string
+		want     map[string]interface{}
+		wantRest []string
+		wantErr  string
+	}{
+		{
+			"flags", withParams, []string{"lang=go", "count=5", "verbose"}, nil,
+			map[string]interface{}{"lang": "go", "count": 5, "verbose": true}, nil, "",
+		},
+		{
+			"positional", withParams, nil, []string{"python", "7"},
+			map[string]interface{}{"lang": "python", "count": 7, "verbose": false}, nil, "",
+		},
+		{
+			"named arguments", withParams, []string{"lang=go"}, []string{"verbose=true", "9"},
+			map[string]interface{}{"lang": "go", "count": 9, "verbose": true}, nil, "",
+		},
+		{
+			"input keeps other arguments", withInput, nil, []string{"lang=go", "explain", "this"},
+			map[string]interface{}{"lang": "go", "count": 3, "verbose": false}, []string{"explain", "this"}, "",
+		},
+		{"missing required", withParams, nil, nil, nil, nil, "missing required parameter lang"},
+		{"bad enum", withParams, []string{"lang=rust"}, nil, nil, nil, `invalid value "rust" for parameter lang`},
+		{"bad int", withParams, []string{"lang=go", "count=x"}, nil, nil, nil, "expected an integer"},
+		{"unknown", withParams, []string{"model=x"}, nil, nil, nil, `unknown parameter "model" (available: lang, count, verbose)`},
+		{"missing value", withParams, []string{"lang"}, nil, nil, nil, "parameter lang needs a value"},
+		{"too many arguments", withParams, nil, []string{"go", "1", "true", "extra"}, nil, nil, "too many arguments: extra"},
+		{"no params", Command{Prompt: "x"}, nil, []string{"a", "b"}, map[string]interface{}{}, []string{"a", "b"}, ""},
+		{
+			"ask answers", withQuestions, []string{"Title=Crash"}, []string{"Urgent=false", "notes"},
+			map[string]interface{}{"Title": "Crash", "Urgent": false}, []string{"notes"}, "",
+		},
+		{
+			"ask confirm", withQuestions, []string{"Urgent"}, nil,
+			map[string]interface{}{"Urgent": true}, nil, "",
+		},
+		{"ask bad answer", withQuestions, []string{"Urgent=maybe"}, nil, nil, nil, "expected true or false"},
+		{"unknown lists questions", withQuestions, []string{"lang=go"}, nil, nil, nil, "(available: Title, Urgent)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := tt.cmd.ParseParams(tt.flags, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseParams() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseParams() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseParams() = %v, want %v", got, tt.want)
+			}
+			if strings.Join(rest, " ") != strings.Join(tt.wantRest, " ") {
+				t.Errorf("rest = %v, want %v", rest, tt.wantRest)
+			}
+		})
+	}
+}
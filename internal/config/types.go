@@ -1,24 +1,144 @@
 package config
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
 // Variable represents a variable definition in command configuration
 type Variable struct {
 	Name string `yaml:"name,omitempty" json:"name,omitempty"`
 	Type string `yaml:"type,omitempty" json:"type,omitempty"`
 	Exec string `yaml:"exec,omitempty" json:"exec,omitempty"`
+
+	// How the exec command runs
+	Dir     string            `yaml:"dir,omitempty"     json:"dir,omitempty"`     // working directory
+	Env     map[string]string `yaml:"env,omitempty"     json:"env,omitempty"`     // added to the environment
+	Timeout string            `yaml:"timeout,omitempty" json:"timeout,omitempty"` // e.g. 10s, 30s when empty
+	Cache   string            `yaml:"cache,omitempty"   json:"cache,omitempty"`   // how long the output is reused, e.g. 5m
+
+	// Sources of the Input variable
+	Glob    string `yaml:"glob,omitempty"     json:"glob,omitempty"`     // files of the glob input type
+	MaxSize int64  `yaml:"max_size,omitempty" json:"max_size,omitempty"` // bytes read from a source
+
+	// Questions of the ask type
+	Ask      string      `yaml:"ask,omitempty"      json:"ask,omitempty"`      // text (default), choice, confirm or editor
+	Question string      `yaml:"question,omitempty" json:"question,omitempty"` // the name when empty
+	Choices  []string    `yaml:"choices,omitempty"  json:"choices,omitempty"`
+	Default  interface{} `yaml:"default,omitempty"  json:"default,omitempty"`
 }
 
 // Command represents a single AI command configuration
 type Command struct {
-	Description  string     `yaml:"description,omitempty"   json:"description,omitempty"`
-	System       string     `yaml:"system,omitempty"        json:"system,omitempty"`
-	Prompt       string     `yaml:"prompt"                  json:"prompt"`
-	Variables    []Variable `yaml:"variables,omitempty"     json:"variables,omitempty"`
-	Input        bool       `yaml:"input,omitempty"         json:"input,omitempty"`
-	InputCommand string     `yaml:"input_command,omitempty" json:"input_command,omitempty"`
-	Files        []string   `yaml:"files,omitempty"         json:"files,omitempty"`
+	Extends      string                 `yaml:"extends,omitempty"       json:"extends,omitempty"` // command inherited from
+	Description  string                 `yaml:"description,omitempty"   json:"description,omitempty"`
+	System       string                 `yaml:"system,omitempty"        json:"system,omitempty"`
+	Prompt       string                 `yaml:"prompt"                  json:"prompt"`
+	SystemFile   string                 `yaml:"system_file,omitempty"   json:"system_file,omitempty"` // read into System
+	PromptFile   string                 `yaml:"prompt_file,omitempty"   json:"prompt_file,omitempty"` // read into Prompt
+	Variables    []Variable             `yaml:"variables,omitempty"     json:"variables,omitempty"`
+	Params       []Param                `yaml:"params,omitempty"        json:"params,omitempty"` // passed on the command line
+	Input        *bool                  `yaml:"input,omitempty"         json:"input,omitempty"`  // unset inherits, see ReadsInput
+	Strict       *bool                  `yaml:"strict,omitempty"        json:"strict,omitempty"` // fail on missing template keys
+	InputCommand string                 `yaml:"input_command,omitempty" json:"input_command,omitempty"`
+	Files        []string               `yaml:"files,omitempty"         json:"files,omitempty"`
+	Output       []OutputStep           `yaml:"output,omitempty"        json:"output,omitempty"`
+	ErrorPattern string                 `yaml:"error_pattern,omitempty" json:"error_pattern,omitempty"`
+	Guards       []Guard                `yaml:"guards,omitempty"        json:"guards,omitempty"`
+	Post         []PostHook             `yaml:"post,omitempty"          json:"post,omitempty"`
+	Pre          []PreCheck             `yaml:"pre,omitempty"           json:"pre,omitempty"`
+	Secrets      string                 `yaml:"secrets,omitempty"       json:"secrets,omitempty"`    // redact, block, warn or off
+	Env          []string               `yaml:"env,omitempty"           json:"env,omitempty"`        // variables exposed as .Env
+	Model        string                 `yaml:"model,omitempty"         json:"model,omitempty"`      // overrides AI_MODEL
+	Parameters   map[string]interface{} `yaml:"parameters,omitempty"    json:"parameters,omitempty"` // temperature, top_p, max_tokens
+
+	Partials  string   `yaml:"-" json:"partials,omitempty"` // define blocks of the config templates
+	Source    string   `yaml:"-" json:"-"`                  // config file defining the command
+	Overrides []string `yaml:"-" json:"-"`                  // config files whose definition this one replaces
+}
+
+// PreCheck is a condition checked before sending the request of a command. Exactly one
+// of Run, NotEmpty and MaxBytes is set.
+type PreCheck struct {
+	Run      string `yaml:"run,omitempty"       json:"run,omitempty"`       // shell command that must succeed
+	NotEmpty bool   `yaml:"not_empty,omitempty" json:"not_empty,omitempty"` // the input must not be blank
+	MaxBytes int    `yaml:"max_bytes,omitempty" json:"max_bytes,omitempty"` // the input must not be larger
+	Message  string `yaml:"message,omitempty"   json:"message,omitempty"`   // shown when the check fails
+}
+
+// PostHook is a shell command run after a successful response. The response is given
+// on stdin and in $AI_RESPONSE, which {{ .Response }} in Run stands for.
+type PostHook struct {
+	Run     string `yaml:"run"               json:"run"`
+	Confirm bool   `yaml:"confirm,omitempty" json:"confirm,omitempty"` // ask before running
+	Edit    bool   `yaml:"edit,omitempty"    json:"edit,omitempty"`    // edit the response first
+}
+
+// Guard is a check on the processed output of a command: the output must match
+// Require, or must not match Reject
+type Guard struct {
+	Require string `yaml:"require,omitempty" json:"require,omitempty"`
+	Reject  string `yaml:"reject,omitempty"  json:"reject,omitempty"`
+	Message string `yaml:"message,omitempty" json:"message,omitempty"`
+}
+
+// OutputStep is a post-processing step applied to the response of a command. It can be
+// written as its type alone, e.g. "trim", or as a mapping with options.
+type OutputStep struct {
+	Type    string `yaml:"type"              json:"type"`
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"` // regex
+	Group   int    `yaml:"group,omitempty"   json:"group,omitempty"`   // regex
+	Width   int    `yaml:"width,omitempty"   json:"width,omitempty"`   // wrap
+}
+
+// UnmarshalYAML accepts both the short and the mapping forms of a step
+func (s *OutputStep) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&s.Type)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch key := node.Content[i]; key.Value {
+		case "type", "pattern", "group", "width":
+		default:
+			return fmt.Errorf("line %d: field %s not found in type config.OutputStep", key.Line, key.Value)
+		}
+	}
+	type plain OutputStep
+	return node.Decode((*plain)(s))
+}
+
+// UnmarshalJSON accepts both the short and the object forms of a step
+func (s *OutputStep) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &s.Type); err == nil {
+		return nil
+	}
+	type plain OutputStep
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode((*plain)(s))
+}
+
+// RedactRule is an extra pattern redacted from saved sessions
+type RedactRule struct {
+	Name    string `yaml:"name"    json:"name"`
+	Pattern string `yaml:"pattern" json:"pattern"`
+}
+
+// SessionConfig controls what is written to saved sessions
+type SessionConfig struct {
+	Redact []RedactRule `yaml:"redact,omitempty" json:"redact,omitempty"`
 }
 
 // Config represents the root configuration structure
 type Config struct {
-	Commands map[string]Command `yaml:"commands" json:"commands"`
+	Include   []string           `yaml:"include,omitempty"   json:"include,omitempty"`   // files or globs, relative to this file
+	Templates []string           `yaml:"templates,omitempty" json:"templates,omitempty"` // partials shared by all commands
+	Commands  map[string]Command `yaml:"commands"            json:"commands"`
+	Session   SessionConfig      `yaml:"session,omitempty"   json:"session,omitempty"`
+
+	Sources  []string `yaml:"-" json:"-"` // loaded files, lowest precedence first
+	partials []string // contents of the template files
 }
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestOutputStepUnmarshal(t *testing.T) {
+	want := []OutputStep{{Type: "trim"}, {Type: "wrap", Width: 72}}
+
+	var fromYAML Command
+	if err := yaml.Unmarshal([]byte("prompt: x\noutput:\n  - trim\n  - type: wrap\n    width: 72\n"), &fromYAML); err != nil {
+		t.Fatalf("yaml.Unmarshal() error = %v", err)
+	}
+	var fromJSON Command
+	if err := json.Unmarshal([]byte(`{"prompt":"x","output":["trim",{"type":"wrap","width":72}]}`), &fromJSON); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	for name, got := range map[string][]OutputStep{"yaml": fromYAML.Output, "json": fromJSON.Output} {
+		if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("%s steps = %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestCommandEnvNames(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  Command
+		want []string
+	}{
+		{"allow list", Command{Env: []string{"HOME", "GIT_*"}, Prompt: "{{ .Env.USER }}"}, []string{"HOME", "GIT_*"}},
+		{
+			"referenced",
+			Command{System: "You run as {{ .Env.USER }}", Prompt: `{{ index .Env "SHELL" }} {{ .Input }}`},
+			[]string{"USER", "SHELL"},
+		},
+		{"none", Command{Prompt: "{{ .Input }}"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.cmd.EnvNames()
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("EnvNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,483 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/y0ug/ai-helper/internal/io"
+	"github.com/y0ug/ai-helper/internal/prompt"
+	"github.com/y0ug/ai-helper/internal/secret"
+)
+
+// Problem is a configuration error, located in its file when known
+type Problem struct {
+	File    string // config or template file
+	Line    int    // 0 when unknown
+	Column  int    // 0 when unknown
+	Command string // command at fault, if any
+	Message string
+}
+
+func (p Problem) Error() string {
+	var sb strings.Builder
+	if p.File != "" {
+		sb.WriteString(p.File)
+		if p.Line > 0 {
+			fmt.Fprintf(&sb, ":%d", p.Line)
+			if p.Column > 0 {
+				fmt.Fprintf(&sb, ":%d", p.Column)
+			}
+		}
+		sb.WriteString(": ")
+	}
+	if p.Command != "" {
+		fmt.Fprintf(&sb, "command '%s': ", p.Command)
+	}
+	sb.WriteString(p.Message)
+	return sb.String()
+}
+
+// VariableTypes lists the supported variable types. The Input variable combines the
+// input sources with "|", e.g. "stdin|arg"; other variables are exec or ask.
+var VariableTypes = append(append([]string{}, io.InputTypes...), "ask")
+
+// AskKinds lists the kinds of questions of ask variables
+var AskKinds = []string{"text", "choice", "confirm", "editor"}
+
+var identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Validate checks the name and type of a variable
+func (v Variable) Validate() error {
+	if !identRe.MatchString(v.Name) {
+		return fmt.Errorf("variable name %q is not a valid identifier", v.Name)
+	}
+	// Input names the command input, the other built-in fields can't be variables
+	if v.Name != "Input" && contains(prompt.BuiltinFields, v.Name) {
+		return fmt.Errorf("variable name %s is reserved for the template data", v.Name)
+	}
+
+	if v.Name == "Input" {
+		if v.Type == "" {
+			return nil
+		}
+		types := strings.Split(v.Type, "|")
+		for _, t := range types {
+			if !contains(io.InputTypes, t) {
+				return fmt.Errorf("unknown input type %q (valid: %s)", t, strings.Join(io.InputTypes, ", "))
+			}
+			if t == "exec" && v.Exec == "" {
+				return fmt.Errorf("input type exec needs an exec command")
+			}
+		}
+		if contains(types, "glob") != (v.Glob != "") {
+			return fmt.Errorf("a glob pattern is required for, and only allowed with, the glob input type")
+		}
+		if v.MaxSize < 0 {
+			return fmt.Errorf("max_size must be positive")
+		}
+		return v.validateExecOptions()
+	}
+
+	if v.Glob != "" || v.MaxSize != 0 {
+		return fmt.Errorf("variable %s: glob and max_size only apply to the Input variable", v.Name)
+	}
+	switch v.Type {
+	case "exec":
+		if strings.TrimSpace(v.Exec) == "" {
+			return fmt.Errorf("variable %s of type exec needs an exec command", v.Name)
+		}
+		return v.validateExecOptions()
+	case "ask":
+		if v.Ask != "" && !contains(AskKinds, v.Ask) {
+			return fmt.Errorf("unknown ask kind %q for variable %s (valid: %s)", v.Ask, v.Name, strings.Join(AskKinds, ", "))
+		}
+		if (v.Ask == "choice") != (len(v.Choices) > 0) {
+			return fmt.Errorf("variable %s: choices are required for, and only allowed on, choice questions", v.Name)
+		}
+		if v.Default != nil {
+			if _, err := v.ParseAnswer(fmt.Sprint(v.Default)); err != nil {
+				return fmt.Errorf("invalid default: %w", err)
+			}
+		}
+		if v.Dir != "" || v.Env != nil || v.Timeout != "" || v.Cache != "" {
+			return fmt.Errorf("variable %s: dir, env, timeout and cache only apply to exec commands", v.Name)
+		}
+	case "":
+		return fmt.Errorf("variable %s needs a type (valid: exec, ask)", v.Name)
+	default:
+		return fmt.Errorf("unknown type %q for variable %s (valid: exec, ask)", v.Type, v.Name)
+	}
+	return nil
+}
+
+// validateExecOptions checks the options of the exec command of a variable
+func (v Variable) validateExecOptions() error {
+	if v.Exec == "" && (v.Dir != "" || v.Env != nil || v.Timeout != "" || v.Cache != "") {
+		return fmt.Errorf("variable %s: dir, env, timeout and cache only apply to exec commands", v.Name)
+	}
+	_, err := v.ExecOptions()
+	return err
+}
+
+// ParseAnswer converts the answer to an ask variable, given on the command line or
+// as its default, to its value: a bool for confirm questions, a string otherwise
+func (v Variable) ParseAnswer(value string) (interface{}, error) {
+	switch v.Ask {
+	case "confirm":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s, expected true or false", value, v.Name)
+		}
+		return b, nil
+	case "choice":
+		if !contains(v.Choices, value) {
+			return nil, fmt.Errorf("invalid value %q for %s (valid: %s)", value, v.Name, strings.Join(v.Choices, ", "))
+		}
+	}
+	return value, nil
+}
+
+// ValidateConfig checks if the configuration is valid, returning its first problem
+func (c *Config) ValidateConfig() error {
+	if problems := c.Problems(false); len(problems) > 0 {
+		return problems[0]
+	}
+	return nil
+}
+
+// Problems checks the whole configuration and returns every problem found, ordered by
+// command. With checkFiles, the files of each command must also exist, relative to
+// the current directory.
+func (c *Config) Problems(checkFiles bool) []Problem {
+	var problems []Problem
+	if len(c.Commands) == 0 {
+		problems = append(problems, Problem{Message: "no commands defined in configuration"})
+	}
+
+	for i, rule := range c.Session.Redact {
+		if rule.Name == "" || rule.Pattern == "" {
+			problems = append(problems, Problem{Message: fmt.Sprintf("session redact rule %d needs a name and a pattern", i+1)})
+		} else if _, err := regexp.Compile(rule.Pattern); err != nil {
+			problems = append(problems, Problem{Message: fmt.Sprintf("invalid session redact rule '%s': %v", rule.Name, err)})
+		}
+	}
+
+	// Partials are shared, so they are checked once rather than with every command
+	partialsOK := true
+	if _, err := prompt.Parse("", strings.Join(c.partials, "\n")); err != nil {
+		problems = append(problems, Problem{Message: fmt.Sprintf("invalid templates: %v", err)})
+		partialsOK = false
+	}
+
+	names := make([]string, 0, len(c.Commands))
+	for name := range c.Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	loc := newLocator()
+	for _, name := range names {
+		cmd := c.Commands[name]
+		var issues []issue
+		if _, err := c.extendsChain(name); err != nil {
+			issues = append(issues, issue{path: []string{"extends"}, message: err.Error()})
+		}
+		issues = append(issues, cmd.issues(checkFiles, partialsOK)...)
+		for _, is := range issues {
+			problems = append(problems, loc.problem(cmd, name, is))
+		}
+	}
+
+	return problems
+}
+
+// issue is a problem of a command, located by the keys leading to the faulty field
+type issue struct {
+	path    []string // keys from the command, e.g. variables, 0, type
+	message string
+	file    string // file holding the faulty text instead of the config, e.g. a prompt_file
+	line    int    // line within the faulty text, e.g. of a template error
+}
+
+// issues checks the fields of a single command
+func (cmd Command) issues(checkFiles, checkTemplates bool) []issue {
+	var issues []issue
+	add := func(message string, path ...string) {
+		issues = append(issues, issue{path: path, message: message})
+	}
+
+	if cmd.Prompt == "" {
+		add("empty prompt", "prompt")
+	}
+
+	declared := make(map[string]bool)
+	for i, v := range cmd.Variables {
+		index := strconv.Itoa(i)
+		if err := v.Validate(); err != nil {
+			add(err.Error(), "variables", index)
+		} else if declared[v.Name] {
+			add(fmt.Sprintf("variable %s declared twice", v.Name), "variables", index)
+		}
+		declared[v.Name] = true
+	}
+	for i, p := range cmd.Params {
+		index := strconv.Itoa(i)
+		if err := p.Validate(); err != nil {
+			add(err.Error(), "params", index)
+		} else if declared[p.Name] {
+			add(fmt.Sprintf("parameter %s has the name of another parameter or variable", p.Name), "params", index)
+		}
+		declared[p.Name] = true
+	}
+
+	if checkTemplates {
+		for _, t := range []struct{ key, text, file string }{
+			{"system", cmd.System, cmd.SystemFile},
+			{"prompt", cmd.Prompt, cmd.PromptFile},
+		} {
+			issues = append(issues, templateIssues(t.key, t.text, t.file, cmd.Partials, declared)...)
+		}
+	}
+
+	for i, step := range cmd.Output {
+		if err := step.Validate(); err != nil {
+			add(fmt.Sprintf("invalid output step %d: %v", i+1, err), "output", strconv.Itoa(i))
+		}
+	}
+	for i, pattern := range cmd.Env {
+		if _, err := path.Match(pattern, ""); err != nil {
+			add(fmt.Sprintf("invalid env pattern %q: %v", pattern, err), "env", strconv.Itoa(i))
+		}
+	}
+	if _, err := secret.ParsePolicy(cmd.Secrets); err != nil {
+		add(fmt.Sprintf("invalid secrets policy: %v", err), "secrets")
+	}
+	if _, err := regexp.Compile(cmd.ErrorPattern); err != nil {
+		add(fmt.Sprintf("invalid error_pattern: %v", err), "error_pattern")
+	}
+	for i, check := range cmd.Pre {
+		if err := check.Validate(); err != nil {
+			add(fmt.Sprintf("invalid pre check %d: %v", i+1, err), "pre", strconv.Itoa(i))
+		}
+	}
+	for i, hook := range cmd.Post {
+		if strings.TrimSpace(hook.Run) == "" {
+			add(fmt.Sprintf("empty run for post hook %d", i+1), "post", strconv.Itoa(i))
+		}
+	}
+	for i, guard := range cmd.Guards {
+		if err := guard.Validate(); err != nil {
+			add(fmt.Sprintf("invalid guard %d: %v", i+1, err), "guards", strconv.Itoa(i))
+		}
+	}
+
+	if checkFiles {
+		for i, pattern := range cmd.Files {
+			if matches, err := filepath.Glob(pattern); err != nil || len(matches) == 0 {
+				add(fmt.Sprintf("no file matches %s", pattern), "files", strconv.Itoa(i))
+			}
+		}
+	}
+
+	return issues
+}
+
+// templateErrLineRe extracts the line of a text/template error, e.g. "template: prompt:3: ..."
+var templateErrLineRe = regexp.MustCompile(`template: prompt:(\d+):`)
+
+// templateIssues parses a template with the real helper functions and reports the
+// fields of the template data it references that are neither built in nor declared
+func templateIssues(key, text, file, partials string, declared map[string]bool) []issue {
+	if text == "" {
+		return nil
+	}
+
+	tmpl, err := prompt.Parse(text, partials)
+	if err != nil {
+		is := issue{path: []string{key}, message: fmt.Sprintf("invalid %s template: %v", key, err), file: file}
+		if m := templateErrLineRe.FindStringSubmatch(err.Error()); m != nil {
+			is.line, _ = strconv.Atoi(m[1])
+		}
+		return []issue{is}
+	}
+
+	var issues []issue
+	seen := make(map[string]bool)
+	walkFields(tmpl.Tree.Root, func(ident []string, pos parse.Pos) {
+		var name string
+		switch {
+		case ident[0] == "Vars" && len(ident) > 1 && !declared[ident[1]]:
+			name = ".Vars." + ident[1]
+		case !templateFields[ident[0]] && !declared[ident[0]]:
+			name = "." + ident[0]
+		}
+		if name != "" && !seen[name] {
+			seen[name] = true
+			issues = append(issues, issue{
+				path:    []string{key},
+				message: fmt.Sprintf("%s template references %s, which is not a declared variable or parameter", key, name),
+				file:    file,
+				line:    strings.Count(text[:pos], "\n") + 1,
+			})
+		}
+	})
+	return issues
+}
+
+// templateFields are the fields of the data given to every template
+var templateFields = map[string]bool{"Input": true, "Env": true, "Files": true, "Vars": true}
+
+// walkFields calls visit with the fields a template reads from its root data and their
+// offset, e.g. ["Vars", "Lang"] for .Vars.Lang or index .Vars "Lang". Fields inside
+// range and with blocks are skipped, their dot being another value.
+func walkFields(node parse.Node, visit func(ident []string, pos parse.Pos)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkFields(child, visit)
+		}
+	case *parse.ActionNode:
+		walkFields(n.Pipe, visit)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			walkFields(cmd, visit)
+		}
+	case *parse.CommandNode:
+		if len(n.Args) >= 3 {
+			id, isIdent := n.Args[0].(*parse.IdentifierNode)
+			field, isField := n.Args[1].(*parse.FieldNode)
+			key, isString := n.Args[2].(*parse.StringNode)
+			if isIdent && id.Ident == "index" && isField && isString && len(field.Ident) == 1 && field.Ident[0] == "Vars" {
+				visit([]string{"Vars", key.Text}, key.Pos)
+			}
+		}
+		for _, arg := range n.Args {
+			walkFields(arg, visit)
+		}
+	case *parse.FieldNode:
+		visit(n.Ident, n.Pos)
+	case *parse.ChainNode:
+		walkFields(n.Node, visit)
+	case *parse.IfNode:
+		walkFields(n.Pipe, visit)
+		walkFields(n.List, visit)
+		walkFields(n.ElseList, visit)
+	case *parse.RangeNode:
+		walkFields(n.Pipe, visit)
+	case *parse.WithNode:
+		walkFields(n.Pipe, visit)
+	case *parse.TemplateNode:
+		walkFields(n.Pipe, visit)
+	}
+}
+
+// locator finds the position of command fields in YAML config files
+type locator struct {
+	docs map[string]*yaml.Node
+}
+
+func newLocator() *locator {
+	return &locator{docs: make(map[string]*yaml.Node)}
+}
+
+// problem turns an issue of a command into a Problem located in its file
+func (l *locator) problem(cmd Command, name string, is issue) Problem {
+	p := Problem{File: cmd.Source, Command: name, Message: is.message}
+	if is.file != "" {
+		p.File, p.Line = is.file, is.line
+		return p
+	}
+
+	node := l.find(cmd.Source, name, is.path)
+	if node == nil {
+		return p
+	}
+	p.Line, p.Column = node.Line, node.Column
+	if is.line > 0 {
+		// Block scalars start on the line after their indicator
+		if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			p.Line, p.Column = node.Line+is.line, 0
+		} else {
+			p.Line += is.line - 1
+		}
+	}
+	return p
+}
+
+// find returns the deepest node of path below the command in a YAML file, or nil when
+// the file cannot be read as YAML
+func (l *locator) find(file, name string, keys []string) *yaml.Node {
+	ext := strings.ToLower(filepath.Ext(file))
+	if ext != ".yaml" && ext != ".yml" {
+		return nil
+	}
+
+	doc, ok := l.docs[file]
+	if !ok {
+		var root yaml.Node
+		if data, err := os.ReadFile(file); err == nil && yaml.Unmarshal(data, &root) == nil && len(root.Content) > 0 {
+			doc = root.Content[0]
+		}
+		l.docs[file] = doc
+	}
+	if doc == nil {
+		return nil
+	}
+
+	// Files of commands.d hold the command itself
+	node := doc
+	if commands := yamlChild(doc, "commands"); commands != nil {
+		if node = yamlChild(commands, name); node == nil {
+			return doc
+		}
+	}
+	for _, key := range keys {
+		next := yamlChild(node, key)
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return node
+}
+
+// yamlChild returns the value of a mapping key or the item of a sequence index
+func yamlChild(node *yaml.Node, key string) *yaml.Node {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				return node.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(node.Content) {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVariableValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		v       Variable
+		wantErr string
+	}{
+		{"input", Variable{Name: "Input", Type: "stdin|arg"}, ""},
+		{"input with exec", Variable{Name: "Input", Type: "stdin|exec", Exec: "git diff"}, ""},
+		{"input without type", Variable{Name: "Input"}, ""},
+		{"input exec without command", Variable{Name: "Input", Type: "arg|exec"}, "needs an exec command"},
+		{"input unknown type", Variable{Name: "Input", Type: "stdin|url"}, `unknown input type "url"`},
+		{"input sources", Variable{Name: "Input", Type: "file|glob|clipboard", Glob: "*.md", MaxSize: 4096}, ""},
+		{"input glob without pattern", Variable{Name: "Input", Type: "arg|glob"}, "glob pattern is required"},
+		{"input pattern without glob", Variable{Name: "Input", Type: "arg", Glob: "*.md"}, "glob pattern is required"},
+		{"input negative max size", Variable{Name: "Input", Type: "stdin", MaxSize: -1}, "max_size must be positive"},
+		{"max size on other variable", Variable{Name: "Log", Type: "exec", Exec: "git log", MaxSize: 10}, "only apply to the Input variable"},
+		{"exec", Variable{Name: "Branch", Type: "exec", Exec: "git branch --show-current"}, ""},
+		{"exec without command", Variable{Name: "Branch", Type: "exec"}, "needs an exec command"},
+		{"missing type", Variable{Name: "Branch"}, "needs a type"},
+		{"unknown type", Variable{Name: "Branch", Type: "shell"}, `unknown type "shell"`},
+		{"bad name", Variable{Name: "my-var", Type: "exec", Exec: "true"}, "not a valid identifier"},
+		{"reserved name", Variable{Name: "Env", Type: "exec", Exec: "env"}, "reserved for the template data"},
+		{"reserved ask name", Variable{Name: "Files", Type: "ask"}, "reserved for the template data"},
+		{"ask", Variable{Name: "Title", Type: "ask", Question: "Bug title"}, ""},
+		{"ask choice", Variable{Name: "Sev", Type: "ask", Ask: "choice", Choices: []string{"low", "high"}, Default: "low"}, ""},
+		{"ask confirm", Variable{Name: "Ok", Type: "ask", Ask: "confirm", Default: true}, ""},
+		{"ask unknown kind", Variable{Name: "Ok", Type: "ask", Ask: "password"}, `unknown ask kind "password"`},
+		{"ask choice without choices", Variable{Name: "Sev", Type: "ask", Ask: "choice"}, "choices are required"},
+		{"ask bad default", Variable{Name: "Sev", Type: "ask", Ask: "choice", Choices: []string{"low"}, Default: "mid"}, "invalid default"},
+		{"ask as input type", Variable{Name: "Input", Type: "stdin|ask"}, `unknown input type "ask"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.v.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigProblemsTemplates(t *testing.T) {
+	branch := []Variable{{Name: "Branch", Type: "exec", Exec: "git branch"}}
+	tests := []struct {
+		name string
+		cmd  Command
+		want []string
+	}{
+		{"valid", Command{Prompt: `{{ .Input }} {{ formatFile "a.go" }} {{ .Env.HOME }}`}, nil},
+		{"declared variable", Command{Prompt: `{{ .Branch }} {{ .Vars.Branch }} {{ index .Vars "Branch" }}`, Variables: branch}, nil},
+		{"declared param", Command{Prompt: `{{ .Vars.lang }} {{ if .Vars.brief }}brief{{ end }}`, Params: []Param{{Name: "lang"}, {Name: "brief", Type: "bool"}}}, nil},
+		{"undefined variable", Command{Prompt: "{{ .Input }}\n{{ .Brnach }}", Variables: branch}, []string{"references .Brnach"}},
+		{"undefined var", Command{Prompt: `{{ index .Vars "Lang" }} {{ .Vars.Lang }}`}, []string{"references .Vars.Lang"}},
+		{"range dot", Command{Prompt: "{{ range $p, $c := .Files }}{{ $p }}{{ end }}{{ range .Files }}{{ .Anything }}{{ end }}"}, nil},
+		{"unknown function", Command{Prompt: `{{ readFile "a" }}`}, []string{`function "readFile" not defined`}},
+		{"parse error", Command{System: "{{ if .Input }}", Prompt: "x"}, []string{"invalid system template"}},
+		{"partial", Command{Prompt: `{{ template "rules" . }}`, Partials: `{{ define "rules" }}be brief{{ end }}`}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Commands: map[string]Command{"cmd": tt.cmd}}
+			problems := cfg.Problems(false)
+			if len(problems) != len(tt.want) {
+				t.Fatalf("Problems() = %v, want %d problem(s)", problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(problems[i].Error(), want) {
+					t.Errorf("problem %d = %v, want it to contain %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigProblemsPositions(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, "ai-helper.yaml", `commands:
+  ask:
+    prompt: |
+      {{ .Input }}
+      {{ .Vars.Missing }}
+    variables:
+      - name: Input
+        type: stdin|pipe
+    files:
+      - `+filepath.Join(dir, "ai-helper.yaml")+`
+      - `+filepath.Join(dir, "missing.md")+`
+  fix:
+    prompt: "{{ if }}"
+`)
+
+	cfg, err := NewLoader().Read([]string{path})
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	want := []string{
+		path + `:7:9: command 'ask': unknown input type "pipe"`,
+		path + ":5: command 'ask': prompt template references .Vars.Missing",
+		path + ":11:9: command 'ask': no file matches",
+		path + ":13:13: command 'fix': invalid prompt template",
+	}
+	problems := cfg.Problems(true)
+	if len(problems) != len(want) {
+		t.Fatalf("Problems() = %v, want %d problems", problems, len(want))
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(problems[i].Error(), w) {
+			t.Errorf("problem %d = %q, want prefix %q", i, problems[i], w)
+		}
+	}
+
+	if got := cfg.Problems(false); len(got) != len(want)-1 {
+		t.Errorf("Problems(false) = %v, want the files unchecked", got)
+	}
+}
+
+func TestLoaderRejectsUnknownKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{"command key", "a.yaml", "commands:\n  ask:\n    promt: x\n", "line 3: field promt not found"},
+		{"root key", "a.yaml", "comands:\n  ask:\n    prompt: x\n", "line 1: field comands not found"},
+		{"output step key", "a.yaml", "commands:\n  ask:\n    prompt: x\n    output:\n      - type: wrap\n        widht: 60\n", "line 6: field widht not found"},
+		{"json key", "a.json", `{"commands": {"ask": {"prompt": "x", "sytem": "y"}}}`, `unknown field "sytem"`},
+		{"json syntax", "a.json", "{\n\"commands\": {,}}", "a.json:2:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := writeConfig(t, dir, tt.file, tt.content)
+			_, err := NewLoader().Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepositoryConfigIsValid(t *testing.T) {
+	path := filepath.Join("..", "..", "config.yaml")
+	if _, err := os.Stat(path); err != nil {
+		t.Skip("config.yaml not found")
+	}
+	cfg, err := NewLoader().Read([]string{path})
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	for _, p := range cfg.Problems(false) {
+		t.Error(p)
+	}
+}
@@ -0,0 +1,114 @@
+// Package hook runs the shell commands declared around a command: pre checks before the
+// request and post hooks acting on the response
+package hook
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+
+	"github.com/y0ug/ai-helper/internal/config"
+	"github.com/y0ug/ai-helper/internal/io"
+)
+
+// Data holds the template variables available to hook commands
+type Data struct {
+	Input    string
+	Response string
+}
+
+// fields are the template variables as rendered in commands: quoted references to the
+// environment variables holding them
+var fields = map[string]string{"Input": `"$AI_INPUT"`, "Response": `"$AI_RESPONSE"`}
+
+// Render executes the template of a hook command. The values are never put in the
+// command, which the shell parses: {{ .Response }} becomes "$AI_RESPONSE" and
+// {{ .Input }} "$AI_INPUT", environment variables set by the command runner.
+func Render(run string) (string, error) {
+	tmpl, err := template.New("hook").Option("missingkey=error").Parse(run)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse hook %q: %w", run, err)
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, fields); err != nil {
+		return "", fmt.Errorf("failed to render hook %q: %w", run, err)
+	}
+	return sb.String(), nil
+}
+
+// maxEnvValue is the largest value set in $AI_INPUT or $AI_RESPONSE. Linux refuses to
+// run a command with an environment string over 128 KiB.
+const maxEnvValue = 120 << 10
+
+// command returns the shell command running a rendered hook, with stdin set to stdin.
+// The data is only put in the environment when the command references it, so a hook
+// reading stdin works whatever the size of the input and response.
+func command(run string, data Data, stdin string) (*exec.Cmd, error) {
+	cmd := exec.Command("sh", "-c", run)
+	cmd.Env = os.Environ()
+	for _, v := range []struct{ name, desc, value string }{
+		{"AI_INPUT", "input", data.Input},
+		{"AI_RESPONSE", "response", data.Response},
+	} {
+		if !strings.Contains(run, v.name) {
+			continue
+		}
+		if len(v.value) > maxEnvValue {
+			return nil, fmt.Errorf("%s is %d bytes, too large for $%s (%d at most), read it from stdin instead", v.desc, len(v.value), v.name, maxEnvValue)
+		}
+		cmd.Env = append(cmd.Env, v.name+"="+v.value)
+	}
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd, nil
+}
+
+// RunPost runs the post hooks in order with the response on stdin. A hook with Edit set
+// opens the response in the editor first, and the edited text is used from then on. A
+// hook with Confirm set is skipped if the user declines. The output of hooks goes to
+// stderr so stdout only holds the response.
+func RunPost(hooks []config.PostHook, data Data) error {
+	for _, hook := range hooks {
+		if hook.Edit {
+			edited, err := io.EditText(data.Response)
+			if err != nil {
+				return err
+			}
+			if edited == "" {
+				return fmt.Errorf("response is empty after editing, hooks aborted")
+			}
+			data.Response = edited
+		}
+
+		run, err := Render(hook.Run)
+		if err != nil {
+			return err
+		}
+
+		if hook.Confirm {
+			if hook.Edit {
+				fmt.Fprintf(os.Stderr, "\n%s\n\n", data.Response)
+			}
+			ok, err := io.Confirm(fmt.Sprintf("Run %q?", run))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Skipped: %s\n", run)
+				continue
+			}
+		}
+
+		cmd, err := command(run, data, data.Response)
+		if err != nil {
+			return fmt.Errorf("hook %q: %w", run, err)
+		}
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("hook %q failed: %w", run, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package hook
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/y0ug/ai-helper/internal/config"
+)
+
+// quote quotes s for use as a single shell word
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     string
+		want    string
+		wantErr bool
+	}{
+		{"plain", "git commit -F -", "git commit -F -", false},
+		{"response", "git commit -m {{ .Response }}", `git commit -m "$AI_RESPONSE"`, false},
+		{"input", "echo {{ .Input }}", `echo "$AI_INPUT"`, false},
+		{"unknown field", "echo {{ .Missing }}", "", true},
+		{"bad template", "echo {{ .Response", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.run)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunPost(t *testing.T) {
+	dir := t.TempDir()
+	stdinFile := filepath.Join(dir, "stdin")
+	argFile := filepath.Join(dir, "arg")
+
+	hooks := []config.PostHook{
+		{Run: "cat > " + quote(stdinFile)},
+		{Run: "printf %s {{ .Response }} > " + quote(argFile)},
+	}
+	if err := RunPost(hooks, Data{Response: "feat: add hooks"}); err != nil {
+		t.Fatalf("RunPost() error = %v", err)
+	}
+	for _, path := range []string{stdinFile, argFile} {
+		if data, err := os.ReadFile(path); err != nil || string(data) != "feat: add hooks" {
+			t.Errorf("%s = %q (%v), want the response", filepath.Base(path), data, err)
+		}
+	}
+
+	// Model output is never parsed by the shell
+	pwned := filepath.Join(dir, "pwned")
+	response := "fix: it's done $(touch " + pwned + ") `touch " + pwned + "`; touch " + pwned
+	hooks = []config.PostHook{{Run: "printf %s {{ .Response }} > " + quote(argFile) + "; printf %s {{ .Input }} > " + quote(stdinFile)}}
+	if err := RunPost(hooks, Data{Input: "a 'diff'; touch " + pwned, Response: response}); err != nil {
+		t.Fatalf("RunPost() error = %v", err)
+	}
+	if _, err := os.Stat(pwned); err == nil {
+		t.Error("the response was run by the shell")
+	}
+	if data, _ := os.ReadFile(argFile); string(data) != response {
+		t.Errorf("response passed as %q, want %q", data, response)
+	}
+	if data, _ := os.ReadFile(stdinFile); string(data) != "a 'diff'; touch "+pwned {
+		t.Errorf("input passed as %q", data)
+	}
+
+	if err := RunPost([]config.PostHook{{Run: "exit 3"}}, Data{}); err == nil {
+		t.Error("RunPost() with a failing hook should return an error")
+	}
+}
+
+func TestRunPostLargeData(t *testing.T) {
+	// Over the 128 KiB Linux allows for an environment string
+	data := Data{Input: strings.Repeat("i", 150000), Response: strings.Repeat("r", 150000)}
+	out := filepath.Join(t.TempDir(), "out")
+
+	if err := RunPost([]config.PostHook{{Run: "cat > " + quote(out)}}, data); err != nil {
+		t.Fatalf("RunPost() of a hook reading stdin error = %v", err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != data.Response {
+		t.Errorf("hook read %d bytes, want the %d of the response", len(got), len(data.Response))
+	}
+
+	err := RunPost([]config.PostHook{{Run: "printf %s {{ .Response }}"}}, data)
+	if err == nil || !strings.Contains(err.Error(), "too large for $AI_RESPONSE") {
+		t.Errorf("RunPost() of a hook referencing the response error = %v, want it too large", err)
+	}
+}
@@ -0,0 +1,63 @@
+package hook
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/y0ug/ai-helper/internal/config"
+)
+
+// CheckError reports a failed pre check
+type CheckError struct {
+	Message string
+}
+
+func (e *CheckError) Error() string {
+	return e.Message
+}
+
+// RunPre evaluates the pre checks in order and returns a *CheckError for the first one
+// that fails. Run commands get the input on stdin and as {{ .Input }}.
+func RunPre(checks []config.PreCheck, data Data) error {
+	for _, check := range checks {
+		msg, err := runCheck(check, data)
+		if err != nil {
+			return err
+		}
+		if msg == "" {
+			continue
+		}
+		if check.Message != "" {
+			msg = check.Message
+		}
+		return &CheckError{Message: msg}
+	}
+	return nil
+}
+
+// runCheck returns a description of the failure, or "" if the check passes
+func runCheck(check config.PreCheck, data Data) (string, error) {
+	switch {
+	case check.NotEmpty:
+		if strings.TrimSpace(data.Input) == "" {
+			return "input is empty", nil
+		}
+	case check.MaxBytes > 0:
+		if len(data.Input) > check.MaxBytes {
+			return fmt.Sprintf("input is %d bytes, more than the %d allowed", len(data.Input), check.MaxBytes), nil
+		}
+	case check.Run != "":
+		run, err := Render(check.Run)
+		if err != nil {
+			return "", err
+		}
+		cmd, err := command(run, data, data.Input)
+		if err != nil {
+			return "", fmt.Errorf("check %q: %w", run, err)
+		}
+		if err := cmd.Run(); err != nil {
+			return fmt.Sprintf("check %q failed: %v", run, err), nil
+		}
+	}
+	return "", nil
+}
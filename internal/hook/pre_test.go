@@ -0,0 +1,67 @@
+package hook
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/y0ug/ai-helper/internal/config"
+)
+
+func TestRunPre(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []config.PreCheck
+		input  string
+		want   string
+	}{
+		{"no checks", nil, "", ""},
+		{"not empty passes", []config.PreCheck{{NotEmpty: true}}, "diff", ""},
+		{"not empty fails", []config.PreCheck{{NotEmpty: true}}, " \n", "input is empty"},
+		{
+			"custom message",
+			[]config.PreCheck{{NotEmpty: true, Message: "nothing staged"}},
+			"",
+			"nothing staged",
+		},
+		{"max bytes passes", []config.PreCheck{{MaxBytes: 4}}, "abcd", ""},
+		{"max bytes fails", []config.PreCheck{{MaxBytes: 3}}, "abcd", "input is 4 bytes, more than the 3 allowed"},
+		{"run passes", []config.PreCheck{{Run: "grep -q diff"}}, "a diff", ""},
+		{"run fails", []config.PreCheck{{Run: "test -n {{ .Input }}"}}, "", `check "test -n \"$AI_INPUT\"" failed: exit status 1`},
+		{
+			"first failure wins",
+			[]config.PreCheck{{MaxBytes: 10}, {Run: "false", Message: "first"}, {NotEmpty: true}},
+			"",
+			"first",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RunPre(tt.checks, Data{Input: tt.input})
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("RunPre() error = %v, want nil", err)
+				}
+				return
+			}
+			var checkErr *CheckError
+			if !errors.As(err, &checkErr) || checkErr.Message != tt.want {
+				t.Errorf("RunPre() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunPreLargeInput(t *testing.T) {
+	// Over the 128 KiB Linux allows for an environment string
+	input := "diff\n" + strings.Repeat("x", 150000)
+
+	if err := RunPre([]config.PreCheck{{Run: "grep -q diff"}}, Data{Input: input}); err != nil {
+		t.Errorf("RunPre() of a check reading stdin error = %v, want nil", err)
+	}
+	err := RunPre([]config.PreCheck{{Run: "test -n {{ .Input }}"}}, Data{Input: input})
+	if err == nil || !strings.Contains(err.Error(), "too large for $AI_INPUT") {
+		t.Errorf("RunPre() of a check referencing the input error = %v, want it too large", err)
+	}
+}
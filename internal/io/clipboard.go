@@ -0,0 +1,32 @@
+package io
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+)
+
+// CopyToClipboard copies text to the system clipboard using the OSC 52 terminal escape
+// sequence, which also works over SSH. The terminal must support and allow OSC 52.
+func CopyToClipboard(text string) error {
+	seq := fmt.Sprintf("\x1b]52;c;%s\x07", base64.StdEncoding.EncodeToString([]byte(text)))
+	// tmux only forwards the sequence to the outer terminal inside a passthrough
+	if os.Getenv("TMUX") != "" {
+		seq = "\x1bPtmux;\x1b" + seq + "\x1b\\"
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		if !IsTerminal(os.Stdout) {
+			return fmt.Errorf("no terminal available for clipboard access")
+		}
+		tty = os.Stdout
+	} else {
+		defer tty.Close()
+	}
+
+	if _, err := tty.WriteString(seq); err != nil {
+		return fmt.Errorf("failed to write to terminal: %w", err)
+	}
+	return nil
+}
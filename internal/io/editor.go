@@ -0,0 +1,59 @@
+package io
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// GetEditor returns the user's preferred editor command
+func GetEditor() string {
+	if editor := os.Getenv("VISUAL"); editor != "" {
+		return editor
+	}
+	if editor := os.Getenv("EDITOR"); editor != "" {
+		return editor
+	}
+	return "vi"
+}
+
+// EditText opens content in the user's editor and returns the edited text
+func EditText(content string) (string, error) {
+	tmpFile, err := os.CreateTemp("", "ai-helper-*.md")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.WriteString(content); err != nil {
+		tmpFile.Close()
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return "", fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	// Run through the shell so EDITOR values with arguments (e.g. "code -w") work
+	cmd := exec.Command("sh", "-c", GetEditor()+` "$1"`, "sh", tmpFile.Name())
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// stdin or stdout may be a pipe, e.g. input piped in or output captured by the shell
+	if !IsTerminal(os.Stdin) || !IsTerminal(os.Stdout) {
+		if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+			defer tty.Close()
+			cmd.Stdin, cmd.Stdout = tty, tty
+		}
+	}
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor exited with error: %w", err)
+	}
+
+	edited, err := os.ReadFile(tmpFile.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited file: %w", err)
+	}
+
+	return strings.TrimSpace(string(edited)), nil
+}
@@ -0,0 +1,198 @@
+package io
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultExecTimeout limits how long a command runs when no timeout is set
+const DefaultExecTimeout = 30 * time.Second
+
+// ExecOptions configures how RunCommand runs a command
+type ExecOptions struct {
+	Dir      string            // working directory, the current one when empty
+	Env      map[string]string // added to the environment
+	Timeout  time.Duration     // DefaultExecTimeout when zero
+	MaxSize  int64             // bytes of output, unlimited when zero
+	CacheTTL time.Duration     // how long the output is reused, not cached when zero
+
+	// Cacheable tells whether an output may be written to the cache, e.g. false when it
+	// holds secrets. Every output is cached when nil.
+	Cacheable func(output string) bool
+}
+
+// RunCommand runs command with sh -c and returns its output. A failing command's error
+// carries what it printed on stderr. With a cache TTL, the output of a successful run is
+// reused by the runs of the same command in the same directory until it expires.
+// Writing to the cache removes the expired entries.
+func RunCommand(command string, opts ExecOptions) (string, error) {
+	var key string
+	if opts.CacheTTL > 0 {
+		key = cacheKey(command, opts)
+		if content, ok := readCache(key, opts.CacheTTL); ok {
+			return content, nil
+		}
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultExecTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = opts.Dir
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), envList(opts.Env)...)
+	}
+	cmd.Stderr = &stderr
+	// Don't wait on processes the shell left behind, still holding stderr
+	cmd.WaitDelay = time.Second
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %q: %w", command, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to run %q: %w", command, err)
+	}
+	// Stop reading at the timeout, even when a leftover process keeps stdout open
+	stop := context.AfterFunc(ctx, func() { stdout.Close() })
+	defer stop()
+
+	var content string
+	if opts.MaxSize > 0 {
+		content, err = readLimited(stdout, opts.MaxSize)
+	} else {
+		var out []byte
+		out, err = io.ReadAll(stdout)
+		content = string(out)
+	}
+	if err != nil {
+		cmd.Process.Kill()
+	}
+	waitErr := cmd.Wait()
+
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return "", fmt.Errorf("%q timed out after %s", command, timeout)
+	case err != nil:
+		return "", err
+	case waitErr != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("failed to run %q: %w: %s", command, waitErr, msg)
+		}
+		return "", fmt.Errorf("failed to run %q: %w", command, waitErr)
+	}
+
+	if key != "" && (opts.Cacheable == nil || opts.Cacheable(content)) {
+		// The cache only saves time, a command is run again when it can't be written
+		writeCache(key, content, opts.CacheTTL)
+	}
+	return content, nil
+}
+
+// envList returns env as KEY=VALUE entries, sorted for a stable cache key
+func envList(env map[string]string) []string {
+	list := make([]string, 0, len(env))
+	for k, v := range env {
+		list = append(list, k+"="+v)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// cacheKey identifies a command by its text, its absolute directory and environment
+func cacheKey(command string, opts ExecOptions) string {
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		dir = opts.Dir
+	}
+	h := sha256.New()
+	for _, part := range append([]string{command, dir}, envList(opts.Env)...) {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func cacheDir() string {
+	return filepath.Join(GetCacheDir(), "exec")
+}
+
+// cacheEntries returns the cache files of key, named <key>-<unix expiry time>
+func cacheEntries(key string) []string {
+	matches, _ := filepath.Glob(filepath.Join(cacheDir(), key+"-*"))
+	return matches
+}
+
+// cacheExpiry returns the expiry time in the name of a cache file
+func cacheExpiry(path string) (time.Time, bool) {
+	base := filepath.Base(path)
+	i := strings.LastIndex(base, "-")
+	if i < 0 {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(base[i+1:], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0), true
+}
+
+// readCache returns the cached output of key when it has not expired and is younger
+// than ttl, which may be shorter than the one it was written with
+func readCache(key string, ttl time.Duration) (string, bool) {
+	for _, path := range cacheEntries(key) {
+		expiry, ok := cacheExpiry(path)
+		info, err := os.Stat(path)
+		if !ok || err != nil || time.Now().After(expiry) || time.Since(info.ModTime()) > ttl {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", false
+		}
+		return string(content), true
+	}
+	return "", false
+}
+
+// writeCache replaces the cached output of key and removes the expired entries
+func writeCache(key, content string, ttl time.Duration) {
+	if err := os.MkdirAll(cacheDir(), 0700); err != nil {
+		return
+	}
+	for _, path := range cacheEntries(key) {
+		os.Remove(path)
+	}
+	path := filepath.Join(cacheDir(), fmt.Sprintf("%s-%d", key, time.Now().Add(ttl).Unix()))
+	os.WriteFile(path, []byte(content), 0600)
+	sweepCache()
+}
+
+// sweepCache removes the expired cache files, and those not named by writeCache
+func sweepCache() {
+	entries, err := os.ReadDir(cacheDir())
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(cacheDir(), entry.Name())
+		if expiry, ok := cacheExpiry(path); !ok || time.Now().After(expiry) {
+			os.Remove(path)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package io
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunCommand(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "marker"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		opts    ExecOptions
+		want    string
+		wantErr string
+	}{
+		{"output", "echo hello", ExecOptions{}, "hello\n", ""},
+		{"dir", "ls", ExecOptions{Dir: dir}, "marker\n", ""},
+		{"env", `echo "$GREETING $NAME"`, ExecOptions{Env: map[string]string{"GREETING": "hi", "NAME": "bob"}}, "hi bob\n", ""},
+		{"stderr", "echo 'fatal: not a git repository' >&2; exit 128", ExecOptions{}, "", "exit status 128: fatal: not a git repository"},
+		{"timeout", "sleep 5", ExecOptions{Timeout: 100 * time.Millisecond}, "", "timed out after 100ms"},
+		{"timeout with a leftover process", "sleep 5; echo done", ExecOptions{Timeout: 100 * time.Millisecond}, "", "timed out"},
+		{"max size", "head -c 1000 /dev/zero", ExecOptions{MaxSize: 100}, "", "more than 100 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			got, err := RunCommand(tt.command, tt.opts)
+			if elapsed := time.Since(start); elapsed > 3*time.Second {
+				t.Errorf("RunCommand() took %s", elapsed)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("RunCommand() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RunCommand() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RunCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCommandCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	counter := filepath.Join(t.TempDir(), "runs")
+	command := "echo run >> " + counter + "; wc -l < " + counter
+
+	run := func(opts ExecOptions) string {
+		t.Helper()
+		out, err := RunCommand(command, opts)
+		if err != nil {
+			t.Fatalf("RunCommand() error = %v", err)
+		}
+		return strings.TrimSpace(out)
+	}
+
+	cached := ExecOptions{CacheTTL: time.Hour}
+	if got := run(cached); got != "1" {
+		t.Fatalf("first run = %q, want 1", got)
+	}
+	if got := run(cached); got != "1" {
+		t.Errorf("cached run = %q, want the first output", got)
+	}
+	if got := run(ExecOptions{CacheTTL: time.Hour, Dir: t.TempDir()}); got != "2" {
+		t.Errorf("run in another directory = %q, want a new run", got)
+	}
+	if got := run(ExecOptions{CacheTTL: time.Nanosecond}); got != "3" {
+		t.Errorf("run with an expired entry = %q, want a new run", got)
+	}
+	if got := run(ExecOptions{}); got != "4" {
+		t.Errorf("uncached run = %q, want a new run", got)
+	}
+
+	refused := ExecOptions{CacheTTL: time.Hour, Env: map[string]string{"A": "b"}, Cacheable: func(string) bool { return false }}
+	if got := run(refused); got != "5" {
+		t.Fatalf("run = %q, want 5", got)
+	}
+	if got := run(refused); got != "6" {
+		t.Errorf("run of an output refused by Cacheable = %q, want a new run", got)
+	}
+}
+
+func TestRunCommandCacheSweep(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	if err := os.MkdirAll(cacheDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	expired := filepath.Join(cacheDir(), fmt.Sprintf("old-%d", time.Now().Add(-time.Minute).Unix()))
+	valid := filepath.Join(cacheDir(), fmt.Sprintf("new-%d", time.Now().Add(time.Hour).Unix()))
+	unnamed := filepath.Join(cacheDir(), "stray")
+	for _, path := range []string{expired, valid, unnamed} {
+		if err := os.WriteFile(path, []byte("out"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := RunCommand("echo hi", ExecOptions{CacheTTL: time.Hour}); err != nil {
+		t.Fatalf("RunCommand() error = %v", err)
+	}
+
+	for path, want := range map[string]bool{expired: false, valid: true, unnamed: false} {
+		if _, err := os.Stat(path); (err == nil) != want {
+			t.Errorf("%s kept = %v, want %v", filepath.Base(path), err == nil, want)
+		}
+	}
+	if entries, _ := os.ReadDir(cacheDir()); len(entries) != 2 {
+		t.Errorf("cache has %d entries, want the valid one and the new one", len(entries))
+	}
+}
@@ -6,31 +6,47 @@ import (
 	"path/filepath"
 )
 
-// FindConfigFile looks for ai-helper config file in standard locations
-func FindConfigFile(dir string) (string, error) {
-	// Try current directory first
-	for _, ext := range []string{".yaml", ".json"} {
-		path := filepath.Join(dir, "ai-helper"+ext)
-		if _, err := os.Stat(path); err == nil {
-			return path, nil
-		}
+// FindConfigFiles returns the config files that apply in dir, lowest precedence first:
+// the global file of the XDG config directory, then every ai-helper file from the root
+// of the git repository containing dir down to dir itself. Outside a repository only
+// dir is searched. A directory with both ai-helper.yaml and ai-helper.json uses the
+// YAML file.
+func FindConfigFiles(dir string) ([]string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", dir, err)
 	}
 
-	// Then try XDG_CONFIG_HOME/ai-helper or ~/.config/ai-helper
-	configDir := os.Getenv("XDG_CONFIG_HOME")
-	if configDir == "" {
-		configDir = filepath.Join(os.Getenv("HOME"), ".config")
+	// Directories from the repository root down to dir
+	dirs := []string{absDir}
+	for d := absDir; ; {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			dirs = []string{absDir}
+			break
+		}
+		d = parent
+		dirs = append([]string{d}, dirs...)
 	}
-	configDir = filepath.Join(configDir, "ai-helper")
 
-	for _, ext := range []string{".yaml", ".json"} {
-		path := filepath.Join(configDir, "ai-helper"+ext)
-		if _, err := os.Stat(path); err == nil {
-			return path, nil
+	var paths []string
+	seen := make(map[string]bool)
+	for _, d := range append([]string{GetConfigDir()}, dirs...) {
+		for _, ext := range []string{".yaml", ".json"} {
+			path := filepath.Join(d, "ai-helper"+ext)
+			if _, err := os.Stat(path); err == nil {
+				if !seen[path] {
+					paths = append(paths, path)
+					seen[path] = true
+				}
+				break
+			}
 		}
 	}
-
-	return "", fmt.Errorf("no config file found in %s or %s", dir, configDir)
+	return paths, nil
 }
 
 // EnsureDirectory ensures the directory exists, creating it if necessary
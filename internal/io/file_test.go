@@ -0,0 +1,54 @@
+package io
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindConfigFiles(t *testing.T) {
+	root := t.TempDir()
+	home := filepath.Join(root, "config")
+	repo := filepath.Join(root, "repo")
+	sub := filepath.Join(repo, "pkg", "sub")
+	for _, d := range []string{filepath.Join(home, "ai-helper"), filepath.Join(repo, ".git"), sub} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := []string{
+		filepath.Join(home, "ai-helper", "ai-helper.yaml"),
+		filepath.Join(root, "ai-helper.yaml"), // above the repository, ignored
+		filepath.Join(repo, "ai-helper.yaml"),
+		filepath.Join(repo, "ai-helper.json"), // shadowed by the YAML file
+		filepath.Join(sub, "ai-helper.json"),
+	}
+	for _, f := range files {
+		if err := os.WriteFile(f, []byte("commands: {}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("XDG_CONFIG_HOME", home)
+
+	tests := []struct {
+		name string
+		dir  string
+		want []string
+	}{
+		{"nested directory", sub, []string{files[0], files[2], files[4]}},
+		{"repository root", repo, []string{files[0], files[2]}},
+		{"outside a repository", root, []string{files[0], files[1]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindConfigFiles(tt.dir)
+			if err != nil {
+				t.Fatalf("FindConfigFiles() error = %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("FindConfigFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
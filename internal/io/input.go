@@ -2,58 +2,313 @@ package io
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 )
 
-// ReadInput reads input based on the specified input types
-func ReadInput(args []string, inputTypes []string, fallbackCmd string) (string, error) {
-	for _, inputType := range inputTypes {
-		switch inputType {
+// InputTypes lists the input sources of ReadInput
+var InputTypes = []string{"arg", "stdin", "file", "glob", "exec", "clipboard"}
+
+// DefaultMaxInputSize is the size limit of a source when the spec sets none
+const DefaultMaxInputSize = 1 << 20
+
+// InputSpec describes where a command reads its input from
+type InputSpec struct {
+	Types       []string    // sources tried in order
+	Exec        string      // command of the exec source
+	ExecOptions ExecOptions // how the exec command runs, its size limit is MaxSize
+	Glob        string      // pattern of the glob source
+	MaxSize     int64       // bytes read from a single source, DefaultMaxInputSize when zero
+}
+
+// errNoInput is returned by a source that has nothing to give, with the reason
+type errNoInput string
+
+func (e errNoInput) Error() string { return string(e) }
+
+// ReadInput reads the input from the first source of the spec that gives one. A source
+// failing or going over the size limit is an error; when none gives an input, the error
+// lists the sources tried and why each gave nothing.
+func ReadInput(args []string, spec InputSpec) (string, error) {
+	limit := spec.MaxSize
+	if limit <= 0 {
+		limit = DefaultMaxInputSize
+	}
+
+	var tried []string
+	for _, source := range spec.Types {
+		var content string
+		var err error
+		switch source {
 		case "arg":
-			if len(args) > 0 {
-				return strings.Join(args, " "), nil
-			}
+			content, err = readArgs(args, limit)
 		case "stdin":
-			stat, err := os.Stdin.Stat()
-			if err != nil {
-				return "", fmt.Errorf("failed to stat stdin: %w", err)
-			}
+			content, err = readStdin(limit)
+		case "file":
+			content, err = readFileArgs(args, limit)
+		case "glob":
+			content, err = readGlob(spec.Glob, limit)
+		case "exec":
+			content, err = readExec(spec.Exec, spec.ExecOptions, limit)
+		case "clipboard":
+			content, err = readClipboard(limit)
+		default:
+			err = fmt.Errorf("unknown input type %q", source)
+		}
 
-			if (stat.Mode() & os.ModeCharDevice) == 0 {
-				reader := bufio.NewReader(os.Stdin)
-				var builder strings.Builder
-
-				for {
-					line, err := reader.ReadString('\n')
-					if err != nil {
-						if err == io.EOF {
-							break
-						}
-						return "", fmt.Errorf("failed to read stdin: %w", err)
-					}
-					builder.WriteString(line)
-				}
-
-				if builder.Len() > 0 {
-					return strings.TrimSpace(builder.String()), nil
-				}
+		var none errNoInput
+		if errors.As(err, &none) {
+			tried = append(tried, fmt.Sprintf("%s (%s)", source, none))
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", source, err)
+		}
+		if content = strings.TrimSpace(content); content == "" {
+			tried = append(tried, source+" (empty)")
+			continue
+		}
+		return content, nil
+	}
+
+	if len(tried) == 0 {
+		return "", fmt.Errorf("no input provided, the command declares no input source")
+	}
+	return "", fmt.Errorf("no input provided, tried %s", strings.Join(tried, ", "))
+}
+
+func readArgs(args []string, limit int64) (string, error) {
+	if len(args) == 0 {
+		return "", errNoInput("no arguments")
+	}
+	content := strings.Join(args, " ")
+	if int64(len(content)) > limit {
+		return "", tooLarge(limit)
+	}
+	return content, nil
+}
+
+func readStdin(limit int64) (string, error) {
+	if IsTerminal(os.Stdin) {
+		return "", errNoInput("nothing piped")
+	}
+	return readLimited(os.Stdin, limit)
+}
+
+// readFileArgs reads the files named by the arguments, unless one is not a file so
+// the arguments can be taken by a following source
+func readFileArgs(args []string, limit int64) (string, error) {
+	if len(args) == 0 {
+		return "", errNoInput("no path argument")
+	}
+	for _, arg := range args {
+		if info, err := os.Stat(arg); err != nil || !info.Mode().IsRegular() {
+			return "", errNoInput(fmt.Sprintf("%s is not a file", arg))
+		}
+	}
+	return readFiles(args, limit)
+}
+
+func readGlob(pattern string, limit int64) (string, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern %s: %w", pattern, err)
+	}
+	var paths []string
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() {
+			paths = append(paths, match)
+		}
+	}
+	if len(paths) == 0 {
+		return "", errNoInput(fmt.Sprintf("no file matches %s", pattern))
+	}
+	return readFiles(paths, limit)
+}
+
+// readFiles reads a single file as is, and several files each under its path in a
+// fenced block. The limit applies to the files together.
+func readFiles(paths []string, limit int64) (string, error) {
+	var sb strings.Builder
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		content, err := readLimited(f, limit-int64(sb.Len()))
+		f.Close()
+		if err != nil {
+			if errors.Is(err, errTooLarge) {
+				return "", tooLarge(limit)
 			}
+			return "", fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if len(paths) == 1 {
+			return content, nil
 		}
+		ext := strings.TrimPrefix(filepath.Ext(path), ".")
+		fmt.Fprintf(&sb, "%s\n```%s\n%s\n```\n\n", path, ext, strings.TrimSpace(content))
 	}
+	return sb.String(), nil
+}
 
-	// If we reach here and have a fallback command, execute it
-	if fallbackCmd != "" {
-		cmd := exec.Command("sh", "-c", fallbackCmd)
-		output, err := cmd.Output()
+func readExec(command string, opts ExecOptions, limit int64) (string, error) {
+	if command == "" {
+		return "", errNoInput("no command")
+	}
+	opts.MaxSize = limit
+	return RunCommand(command, opts)
+}
+
+// clipboardCommands lists the commands reading the clipboard, by preference
+var clipboardCommands = [][]string{
+	{"wl-paste", "--no-newline"},
+	{"xclip", "-selection", "clipboard", "-o"},
+}
+
+func readClipboard(limit int64) (string, error) {
+	for _, args := range clipboardCommands {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+		// wl-paste needs a Wayland session, xclip an X display
+		if args[0] == "wl-paste" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			continue
+		}
+		out, err := exec.Command(args[0], args[1:]...).Output()
+		if err != nil {
+			return "", errNoInput(fmt.Sprintf("%s failed: %v", args[0], err))
+		}
+		if int64(len(out)) > limit {
+			return "", tooLarge(limit)
+		}
+		return string(out), nil
+	}
+	var names []string
+	for _, args := range clipboardCommands {
+		names = append(names, args[0])
+	}
+	return "", errNoInput("no clipboard command found, install " + strings.Join(names, " or "))
+}
+
+var errTooLarge = errors.New("input too large")
+
+func tooLarge(limit int64) error {
+	return fmt.Errorf("%w, more than %d bytes (raise max_size)", errTooLarge, limit)
+}
+
+// readLimited reads r to the end, failing when it holds more than limit bytes
+func readLimited(r io.Reader, limit int64) (string, error) {
+	if limit < 0 {
+		limit = 0
+	}
+	content, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read: %w", err)
+	}
+	if int64(len(content)) > limit {
+		return "", tooLarge(limit)
+	}
+	return string(content), nil
+}
+
+// Confirm asks a yes/no question on the terminal, defaulting to no. It reads from the
+// controlling terminal so it works when stdin is a pipe.
+func Confirm(question string) (bool, error) {
+	return ConfirmDefault(question, false)
+}
+
+// ConfirmDefault asks a yes/no question on the terminal, an empty answer giving def
+func ConfirmDefault(question string, def bool) (bool, error) {
+	hint := "[y/N]"
+	if def {
+		hint = "[Y/n]"
+	}
+	answer, err := readAnswer(fmt.Sprintf("%s %s ", question, hint))
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "":
+		return def, nil
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// Ask asks for a line of text on the terminal, an empty answer giving def
+func Ask(question, def string) (string, error) {
+	prompt := question + ": "
+	if def != "" {
+		prompt = fmt.Sprintf("%s [%s]: ", question, def)
+	}
+	answer, err := readAnswer(prompt)
+	if err != nil {
+		return "", err
+	}
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// Choose asks to pick one of choices, by number or value, an empty answer giving def.
+// The question is asked again until the answer is valid.
+func Choose(question string, choices []string, def string) (string, error) {
+	for i, choice := range choices {
+		fmt.Fprintf(os.Stderr, "  %d) %s\n", i+1, choice)
+	}
+	for {
+		answer, err := Ask(question, def)
 		if err != nil {
-			return "", fmt.Errorf("failed to execute fallback command: %w", err)
+			return "", err
 		}
-		return strings.TrimSpace(string(output)), nil
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(choices) {
+			return choices[n-1], nil
+		}
+		for _, choice := range choices {
+			if answer == choice {
+				return choice, nil
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Invalid choice %q\n", answer)
 	}
+}
 
-	return "", fmt.Errorf("no input provided through specified methods")
+// HasTerminal reports whether questions can be asked on a terminal
+func HasTerminal() bool {
+	if tty, err := os.Open("/dev/tty"); err == nil {
+		tty.Close()
+		return true
+	}
+	return IsTerminal(os.Stdin)
+}
+
+// readAnswer prints prompt on stderr and reads a line from the controlling terminal,
+// so it works when stdin is a pipe
+func readAnswer(prompt string) (string, error) {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		if !IsTerminal(os.Stdin) {
+			return "", fmt.Errorf("no terminal available to ask: %s", strings.TrimSpace(prompt))
+		}
+		tty = os.Stdin
+	} else {
+		defer tty.Close()
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	answer, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+	return strings.TrimSpace(answer), nil
 }
@@ -0,0 +1,79 @@
+package io
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadInput(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	notes := write("notes.txt", "  release notes\n")
+	a := write("a.md", "first")
+	b := write("b.md", "second")
+
+	// An empty stdin and no clipboard tool, whatever the machine running the tests has
+	stdin, err := os.Open(write("stdin", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	defer func(f *os.File) { os.Stdin = f }(os.Stdin)
+	os.Stdin = stdin
+	defer func(commands [][]string) { clipboardCommands = commands }(clipboardCommands)
+	clipboardCommands = [][]string{{"ai-helper-test-paste"}}
+
+	tests := []struct {
+		name    string
+		args    []string
+		spec    InputSpec
+		want    string
+		wantErr string
+	}{
+		{"arg", []string{"hello", "world"}, InputSpec{Types: []string{"stdin", "arg"}}, "hello world", ""},
+		{"file", []string{notes}, InputSpec{Types: []string{"file", "arg"}}, "release notes", ""},
+		{"file falls back to arg", []string{"fix", "typo"}, InputSpec{Types: []string{"file", "arg"}}, "fix typo", ""},
+		{
+			"several files", []string{a, b}, InputSpec{Types: []string{"file"}},
+			a + "\n```md\nfirst\n```\n\n" + b + "\n```md\nsecond\n```", "",
+		},
+		{"glob", nil, InputSpec{Types: []string{"glob"}, Glob: filepath.Join(dir, "*.txt")}, "release notes", ""},
+		{"exec", nil, InputSpec{Types: []string{"arg", "exec"}, Exec: "echo from exec"}, "from exec", ""},
+		{"exec failure", nil, InputSpec{Types: []string{"exec"}, Exec: "echo broken >&2; exit 3"}, "", "exec: failed to run"},
+		{"arg over limit", []string{"0123456789"}, InputSpec{Types: []string{"arg"}, MaxSize: 5}, "", "more than 5 bytes"},
+		{"files over limit", []string{a, b}, InputSpec{Types: []string{"file"}, MaxSize: 20}, "", "file: input too large"},
+		{"exec over limit", nil, InputSpec{Types: []string{"exec"}, Exec: "head -c 1000 /dev/zero", MaxSize: 100}, "", "more than 100 bytes"},
+		{
+			"nothing", nil,
+			InputSpec{Types: []string{"arg", "stdin", "file", "glob", "clipboard"}, Glob: filepath.Join(dir, "*.go")},
+			"", "tried arg (no arguments), stdin (empty), file (no path argument), glob (no file matches " +
+				filepath.Join(dir, "*.go") + "), clipboard (no clipboard command found, install ai-helper-test-paste)",
+		},
+		{"no source", []string{"x"}, InputSpec{}, "", "declares no input source"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadInput(tt.args, tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ReadInput() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadInput() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ReadInput() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
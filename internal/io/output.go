@@ -3,13 +3,50 @@ package io
 import (
 	"fmt"
 	"os"
+
+	"golang.org/x/term"
+
+	"github.com/y0ug/ai-helper/internal/markdown"
 )
 
-// WriteOutput writes the output to either stdout or a file
-func WriteOutput(output string, outputFile string) error {
+// IsTerminal reports whether the file is attached to a terminal
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// RenderEnabled reports whether output written to stdout should be rendered as styled
+// Markdown: stdout must be a terminal and NO_COLOR must not be set
+func RenderEnabled() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return IsTerminal(os.Stdout)
+}
+
+// TerminalWidth returns the width of stdout, or 0 when unknown
+func TerminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// PrintMarkdown prints Markdown content to stdout, styled when rendering is enabled
+func PrintMarkdown(content string, render bool) {
+	if render {
+		fmt.Print(markdown.Render(content, TerminalWidth()))
+		return
+	}
+	fmt.Println(content)
+}
+
+// WriteOutput writes the output to either stdout or a file.
+// Output to stdout is rendered as Markdown when render is true; files always get raw content.
+func WriteOutput(output string, outputFile string, render bool) error {
 	// If no output file specified, write to stdout
 	if outputFile == "" {
-		fmt.Println(output)
+		PrintMarkdown(output, render)
 		return nil
 	}
 
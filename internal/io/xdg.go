@@ -18,3 +18,16 @@ func GetCacheDir() string {
 	}
 	return filepath.Join(cacheHome, "ai-helper")
 }
+
+// GetConfigDir returns the XDG config directory for ai-helper
+func GetConfigDir() string {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return ".config"
+		}
+		configHome = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configHome, "ai-helper")
+}
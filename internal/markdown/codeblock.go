@@ -0,0 +1,99 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CodeBlock is a fenced code block found in Markdown content
+type CodeBlock struct {
+	Lang    string
+	Info    string // rest of the info string after the language, e.g. a file name
+	Caption string // closest non-empty line before the opening fence
+	Code    string
+}
+
+// ExtractCodeBlocks returns the fenced code blocks of content in order of appearance.
+// An unterminated block at the end of content is included.
+func ExtractCodeBlocks(content string) []CodeBlock {
+	var blocks []CodeBlock
+	var current *CodeBlock
+	var fence, indent, caption string
+	var lines []string
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		m := fenceRe.FindStringSubmatch(line)
+
+		if current == nil {
+			if m != nil {
+				current = &CodeBlock{
+					Lang:    m[2],
+					Info:    strings.TrimSpace(strings.TrimPrefix(line, m[0])),
+					Caption: caption,
+				}
+				fence = m[1]
+				indent = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+				lines = nil
+			} else if strings.TrimSpace(line) != "" {
+				caption = strings.TrimSpace(line)
+			}
+			continue
+		}
+
+		if m != nil && m[2] == "" && strings.HasPrefix(m[1], fence[:1]) && len(m[1]) >= len(fence) {
+			current.Code = strings.Join(lines, "\n")
+			blocks = append(blocks, *current)
+			current = nil
+			caption = ""
+			continue
+		}
+		// Blocks nested in lists are indented like their opening fence
+		lines = append(lines, strings.TrimPrefix(line, indent))
+	}
+
+	if current != nil {
+		current.Code = strings.TrimRight(strings.Join(lines, "\n"), "\n")
+		blocks = append(blocks, *current)
+	}
+	return blocks
+}
+
+// SelectCodeBlocks filters blocks by language (case-insensitive, empty for any) and then
+// picks the nth one (1-based, negative counts from the end, 0 for all of them)
+func SelectCodeBlocks(blocks []CodeBlock, lang string, n int) ([]CodeBlock, error) {
+	var selected []CodeBlock
+	for _, b := range blocks {
+		if lang == "" || strings.EqualFold(b.Lang, lang) {
+			selected = append(selected, b)
+		}
+	}
+
+	if len(selected) == 0 {
+		if lang != "" {
+			return nil, fmt.Errorf("no %s code block found", lang)
+		}
+		return nil, fmt.Errorf("no code block found")
+	}
+
+	if n == 0 {
+		return selected, nil
+	}
+	idx := n - 1
+	if n < 0 {
+		idx = len(selected) + n
+	}
+	if idx < 0 || idx >= len(selected) {
+		return nil, fmt.Errorf("code block %d not found, %d available", n, len(selected))
+	}
+	return selected[idx : idx+1], nil
+}
+
+// JoinCodeBlocks returns the code of the blocks separated by blank lines
+func JoinCodeBlocks(blocks []CodeBlock) string {
+	code := make([]string, len(blocks))
+	for i, b := range blocks {
+		code[i] = b.Code
+	}
+	return strings.Join(code, "\n\n")
+}
@@ -0,0 +1,74 @@
+package markdown
+
+import "testing"
+
+const sampleAnswer = "Run this:\n\n```bash\nls -la\n```\n\nThen:\n\n" +
+	"1. Edit the file\n   ```go\n   package main\n   ```\n\n~~~\nplain\n~~~\n"
+
+func TestExtractCodeBlocks(t *testing.T) {
+	blocks := ExtractCodeBlocks(sampleAnswer)
+
+	want := []CodeBlock{
+		{Lang: "bash", Code: "ls -la"},
+		{Lang: "go", Code: "package main"},
+		{Lang: "", Code: "plain"},
+	}
+	if len(blocks) != len(want) {
+		t.Fatalf("ExtractCodeBlocks() returned %d blocks, want %d", len(blocks), len(want))
+	}
+	for i := range want {
+		if blocks[i].Lang != want[i].Lang || blocks[i].Code != want[i].Code {
+			t.Errorf("block %d = %+v, want %+v", i, blocks[i], want[i])
+		}
+	}
+
+	if blocks[1].Caption != "1. Edit the file" {
+		t.Errorf("block 1 caption = %q, want the preceding line", blocks[1].Caption)
+	}
+
+	unterminated := ExtractCodeBlocks("```sh\necho hi\n")
+	if len(unterminated) != 1 || unterminated[0].Code != "echo hi" {
+		t.Errorf("unterminated block = %+v, want echo hi", unterminated)
+	}
+}
+
+func TestSelectCodeBlocks(t *testing.T) {
+	blocks := ExtractCodeBlocks(sampleAnswer)
+
+	tests := []struct {
+		name    string
+		lang    string
+		n       int
+		want    string
+		wantErr bool
+	}{
+		{name: "All", want: "ls -la\n\npackage main\n\nplain"},
+		{name: "Second", n: 2, want: "package main"},
+		{name: "Last", n: -1, want: "plain"},
+		{name: "By Language", lang: "GO", want: "package main"},
+		{name: "Missing Language", lang: "python", wantErr: true},
+		{name: "Out Of Range", n: 4, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := SelectCodeBlocks(blocks, tt.lang, tt.n)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("SelectCodeBlocks() error = nil, wantErr = true")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("SelectCodeBlocks() unexpected error = %v", err)
+				return
+			}
+
+			if got := JoinCodeBlocks(selected); got != tt.want {
+				t.Errorf("SelectCodeBlocks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// ANSI escape sequences. Each style has its own "off" sequence so styles can nest.
+const (
+	bold       = "\x1b[1m"
+	boldOff    = "\x1b[22m"
+	dim        = "\x1b[2m"
+	dimOff     = "\x1b[22m"
+	italic     = "\x1b[3m"
+	italicOff  = "\x1b[23m"
+	underline  = "\x1b[4m"
+	underOff   = "\x1b[24m"
+	strike     = "\x1b[9m"
+	strikeOff  = "\x1b[29m"
+	cyan       = "\x1b[36m"
+	magenta    = "\x1b[35m"
+	yellow     = "\x1b[33m"
+	colorOff   = "\x1b[39m"
+	codeStyle  = "monokai"
+	codeFormat = "terminal256"
+)
+
+var (
+	headingRe    = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	fenceRe      = regexp.MustCompile("^\\s*(```+|~~~+)\\s*([\\w+#.-]*)")
+	bulletRe     = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedRe    = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	quoteRe      = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	ruleRe       = regexp.MustCompile(`^\s*([-*_])(\s*([-*_]))+\s*$`)
+	tableRe      = regexp.MustCompile(`^\s*\|.*\|\s*$`)
+	tableSepRe   = regexp.MustCompile(`^\s*\|?(\s*:?-+:?\s*\|)+\s*:?-*:?\s*$`)
+	codeSpanRe   = regexp.MustCompile("`[^`]+`")
+	boldRe       = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicRe     = regexp.MustCompile(`\*([^*\s][^*]*)\*|\b_([^_]+)_\b`)
+	strikeRe     = regexp.MustCompile(`~~([^~]+)~~`)
+	linkRe       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	ansiRe       = regexp.MustCompile("\x1b\\[[0-9;]*m")
+	placeholders = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// Renderer converts Markdown into ANSI styled text for terminals. It is an io.Writer so
+// content can be rendered while it streams in: lines are written as soon as they are
+// complete, while code blocks and tables are held until they end. Call Close to flush.
+type Renderer struct {
+	w     io.Writer
+	width int
+
+	partial   string   // incomplete last line
+	fence     string   // opening fence of the current code block, empty outside code
+	codeLang  string   // language of the current code block
+	codeLines []string // lines of the current code block
+	table     []string // lines of the current table
+}
+
+// NewRenderer creates a Renderer writing to w, using width for horizontal rules
+func NewRenderer(w io.Writer, width int) *Renderer {
+	if width <= 0 {
+		width = 80
+	}
+	return &Renderer{w: w, width: width}
+}
+
+// Render converts a complete Markdown document
+func Render(content string, width int) string {
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, width)
+	r.Write([]byte(content))
+	r.Close()
+	return buf.String()
+}
+
+// Write renders every complete line in p, keeping any trailing partial line
+func (r *Renderer) Write(p []byte) (int, error) {
+	data := r.partial + string(p)
+	lines := strings.Split(data, "\n")
+	r.partial = lines[len(lines)-1]
+
+	for _, line := range lines[:len(lines)-1] {
+		if err := r.renderLine(strings.TrimSuffix(line, "\r")); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close renders any buffered content, including unterminated code blocks and tables
+func (r *Renderer) Close() error {
+	if r.partial != "" {
+		line := r.partial
+		r.partial = ""
+		if err := r.renderLine(line); err != nil {
+			return err
+		}
+	}
+	if r.fence != "" {
+		if err := r.flushCode(); err != nil {
+			return err
+		}
+	}
+	return r.flushTable()
+}
+
+func (r *Renderer) renderLine(line string) error {
+	// Inside a code block everything is buffered until the closing fence
+	if r.fence != "" {
+		if m := fenceRe.FindStringSubmatch(line); m != nil && m[2] == "" &&
+			strings.HasPrefix(m[1], r.fence[:1]) && len(m[1]) >= len(r.fence) {
+			if err := r.flushCode(); err != nil {
+				return err
+			}
+			return r.print(dim + strings.TrimSpace(line) + dimOff)
+		}
+		r.codeLines = append(r.codeLines, line)
+		return nil
+	}
+
+	if tableRe.MatchString(line) {
+		r.table = append(r.table, line)
+		return nil
+	}
+	if err := r.flushTable(); err != nil {
+		return err
+	}
+
+	if m := fenceRe.FindStringSubmatch(line); m != nil {
+		r.fence = m[1]
+		r.codeLang = m[2]
+		r.codeLines = nil
+		return r.print(dim + strings.TrimSpace(line) + dimOff)
+	}
+
+	switch {
+	case headingRe.MatchString(line):
+		m := headingRe.FindStringSubmatch(line)
+		style := bold + magenta
+		if len(m[1]) == 1 {
+			style += underline
+		}
+		return r.print(style + m[1] + " " + inline(m[2]) + underOff + colorOff + boldOff)
+	case ruleRe.MatchString(line):
+		return r.print(dim + strings.Repeat("─", r.width) + dimOff)
+	case bulletRe.MatchString(line):
+		m := bulletRe.FindStringSubmatch(line)
+		return r.print(m[1] + yellow + "•" + colorOff + " " + inline(m[2]))
+	case orderedRe.MatchString(line):
+		m := orderedRe.FindStringSubmatch(line)
+		return r.print(m[1] + yellow + m[2] + colorOff + " " + inline(m[3]))
+	case quoteRe.MatchString(line):
+		m := quoteRe.FindStringSubmatch(line)
+		return r.print(dim + "│ " + dimOff + italic + inline(m[1]) + italicOff)
+	default:
+		return r.print(inline(line))
+	}
+}
+
+// flushCode writes the buffered code block with syntax highlighting
+func (r *Renderer) flushCode() error {
+	code := strings.Join(r.codeLines, "\n")
+	r.fence, r.codeLines = "", nil
+	if code == "" {
+		return nil
+	}
+	return r.print(Highlight(code, r.codeLang))
+}
+
+// flushTable writes the buffered table with aligned columns
+func (r *Renderer) flushTable() error {
+	if len(r.table) == 0 {
+		return nil
+	}
+	lines := r.table
+	r.table = nil
+
+	var rows [][]string
+	separator := -1
+	for i, line := range lines {
+		if tableSepRe.MatchString(line) {
+			if separator < 0 {
+				separator = i
+			}
+			rows = append(rows, nil)
+			continue
+		}
+		cells := strings.Split(strings.Trim(strings.TrimSpace(line), "|"), "|")
+		for j := range cells {
+			cells[j] = inline(strings.TrimSpace(cells[j]))
+		}
+		rows = append(rows, cells)
+	}
+
+	var widths []int
+	for _, row := range rows {
+		for j, cell := range row {
+			if j >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if w := visibleWidth(cell); w > widths[j] {
+				widths[j] = w
+			}
+		}
+	}
+
+	for i, row := range rows {
+		var sb strings.Builder
+		if row == nil {
+			for j, w := range widths {
+				if j > 0 {
+					sb.WriteString("─┼─")
+				}
+				sb.WriteString(strings.Repeat("─", w))
+			}
+			if err := r.print(dim + sb.String() + dimOff); err != nil {
+				return err
+			}
+			continue
+		}
+		for j, w := range widths {
+			if j > 0 {
+				sb.WriteString(dim + " │ " + dimOff)
+			}
+			cell := ""
+			if j < len(row) {
+				cell = row[j]
+			}
+			if i < separator {
+				cell = bold + cell + boldOff
+			}
+			sb.WriteString(cell + strings.Repeat(" ", w-visibleWidth(cell)))
+		}
+		if err := r.print(strings.TrimRight(sb.String(), " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Renderer) print(s string) error {
+	_, err := fmt.Fprintln(r.w, s)
+	return err
+}
+
+// Highlight returns code colored for a 256 color terminal. The lexer is picked from
+// lang, or guessed from the code when lang is empty or unknown.
+func Highlight(code, lang string) string {
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		lexer = lexers.Analyse(code)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	iterator, err := lexer.Tokenise(nil, code)
+	if err != nil {
+		return code
+	}
+
+	var buf bytes.Buffer
+	if err := formatters.Get(codeFormat).Format(&buf, styles.Get(codeStyle), iterator); err != nil {
+		return code
+	}
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// inline applies emphasis, strike-through, links and code span styles to a line
+func inline(text string) string {
+	// Protect code spans so their content is not styled
+	var spans []string
+	text = codeSpanRe.ReplaceAllStringFunc(text, func(s string) string {
+		spans = append(spans, cyan+strings.Trim(s, "`")+colorOff)
+		return fmt.Sprintf("\x00%d\x00", len(spans)-1)
+	})
+
+	text = linkRe.ReplaceAllString(text, underline+"$1"+underOff+" "+dim+"($2)"+dimOff)
+	text = boldRe.ReplaceAllString(text, bold+"$1$2"+boldOff)
+	text = italicRe.ReplaceAllString(text, italic+"$1$2"+italicOff)
+	text = strikeRe.ReplaceAllString(text, strike+"$1"+strikeOff)
+
+	return placeholders.ReplaceAllStringFunc(text, func(s string) string {
+		var i int
+		fmt.Sscanf(strings.Trim(s, "\x00"), "%d", &i)
+		return spans[i]
+	})
+}
+
+// visibleWidth returns the number of characters displayed, ignoring escape sequences
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(ansiRe.ReplaceAllString(s, ""))
+}
@@ -0,0 +1,99 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "Heading",
+			input:    "# Title",
+			contains: []string{bold, "# Title"},
+		},
+		{
+			name:     "Bullet List",
+			input:    "- item one\n* item two",
+			contains: []string{"•" + colorOff + " item one", "•" + colorOff + " item two"},
+		},
+		{
+			name:     "Emphasis And Code Span",
+			input:    "some **bold** and `**not bold**`",
+			contains: []string{bold + "bold" + boldOff, cyan + "**not bold**" + colorOff},
+		},
+		{
+			name:     "Link",
+			input:    "see [docs](https://example.com)",
+			contains: []string{underline + "docs" + underOff, "(https://example.com)"},
+		},
+		{
+			name:     "Fenced Code",
+			input:    "```go\nfunc main() {}\n```",
+			contains: []string{"```go", "\x1b[", "main"},
+			excludes: []string{"**"},
+		},
+		{
+			name:     "Table",
+			input:    "| a | bb |\n|---|---|\n| ccc | d |",
+			contains: []string{"─┼─", "ccc"},
+		},
+		{
+			name:     "Unterminated Code Block",
+			input:    "```\nls -la",
+			contains: []string{"ls -la"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Render(tt.input, 40)
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("Render() = %q, want it to contain %q", got, want)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(got, unwanted) {
+					t.Errorf("Render() = %q, should not contain %q", got, unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestRendererStreaming(t *testing.T) {
+	input := "# Title\n\nSome *text* here.\n\n```python\nprint('hi')\n```\n| a | b |\n|---|---|\n| 1 | 2 |\n"
+
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, 40)
+	// Feed the content in small chunks, cutting lines and escape-worthy markers
+	for i := 0; i < len(input); i += 3 {
+		end := i + 3
+		if end > len(input) {
+			end = len(input)
+		}
+		if _, err := r.Write([]byte(input[i:end])); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if want := Render(input, 40); buf.String() != want {
+		t.Errorf("streamed output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestVisibleWidth(t *testing.T) {
+	if got := visibleWidth(bold + "héllo" + boldOff); got != 5 {
+		t.Errorf("visibleWidth() = %d, want 5", got)
+	}
+}
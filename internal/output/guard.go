@@ -0,0 +1,79 @@
+package output
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/y0ug/ai-helper/internal/config"
+)
+
+// GuardError reports a response rejected by the error pattern or a guard of a command
+type GuardError struct {
+	Message string
+}
+
+func (e *GuardError) Error() string {
+	return e.Message
+}
+
+// CheckError looks for the error sentinel pattern in a response. The message reported
+// is the first capture group of the pattern if it has one, else the line that matched.
+func CheckError(content, pattern string) error {
+	if pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid error pattern: %w", err)
+	}
+
+	loc := re.FindStringSubmatchIndex(content)
+	if loc == nil {
+		return nil
+	}
+
+	var msg string
+	if len(loc) > 2 && loc[2] >= 0 {
+		msg = content[loc[2]:loc[3]]
+	} else {
+		start := strings.LastIndex(content[:loc[0]], "\n") + 1
+		end := strings.Index(content[loc[0]:], "\n")
+		if end < 0 {
+			msg = content[start:]
+		} else {
+			msg = content[start : loc[0]+end]
+		}
+	}
+
+	msg = strings.Trim(msg, " \t\r\n\"'`")
+	if msg == "" {
+		msg = "the model reported an error"
+	}
+	return &GuardError{Message: msg}
+}
+
+// CheckGuards runs the guards on the output and returns the first failure
+func CheckGuards(content string, guards []config.Guard) error {
+	for _, g := range guards {
+		pattern, reject := g.Pattern()
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid guard pattern: %w", err)
+		}
+
+		if re.MatchString(content) != reject {
+			continue
+		}
+		msg := g.Message
+		switch {
+		case msg != "":
+		case reject:
+			msg = fmt.Sprintf("output matches %q", pattern)
+		default:
+			msg = fmt.Sprintf("output does not match %q", pattern)
+		}
+		return &GuardError{Message: msg}
+	}
+	return nil
+}
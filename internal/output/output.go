@@ -0,0 +1,154 @@
+// Package output post-processes responses with the steps declared by a command
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/y0ug/ai-helper/internal/config"
+)
+
+const defaultWrapWidth = 72
+
+var fencedRe = regexp.MustCompile("^(```+|~~~+)[^\\n]*\\n((?s).*?)\\n?(```+|~~~+)$")
+
+// Process applies the steps in order and returns the result, or the error of the
+// first step that fails
+func Process(content string, steps []config.OutputStep) (string, error) {
+	for i, step := range steps {
+		var err error
+		content, err = Apply(content, step)
+		if err != nil {
+			return "", fmt.Errorf("output step %d (%s): %w", i+1, step.Type, err)
+		}
+	}
+	return content, nil
+}
+
+// Apply runs a single step
+func Apply(content string, step config.OutputStep) (string, error) {
+	switch step.Type {
+	case "trim":
+		return strings.TrimSpace(content), nil
+	case "strip_fences":
+		return StripFences(content), nil
+	case "strip_quotes":
+		return StripQuotes(content), nil
+	case "extract_json":
+		return ExtractJSON(content)
+	case "validate_json":
+		if !json.Valid([]byte(strings.TrimSpace(content))) {
+			return "", fmt.Errorf("response is not valid JSON")
+		}
+		return content, nil
+	case "regex":
+		return Capture(content, step.Pattern, step.Group)
+	case "wrap":
+		width := step.Width
+		if width == 0 {
+			width = defaultWrapWidth
+		}
+		return Wrap(content, width), nil
+	default:
+		return "", fmt.Errorf("unknown step type %q", step.Type)
+	}
+}
+
+// StripFences removes a code fence wrapping the whole content, keeping its code.
+// Content with text outside the fence is returned unchanged.
+func StripFences(content string) string {
+	trimmed := strings.TrimSpace(content)
+	m := fencedRe.FindStringSubmatch(trimmed)
+	if m == nil || m[1][0] != m[3][0] || len(m[3]) < len(m[1]) {
+		return content
+	}
+	// A fence inside the code means several blocks, not a single wrapping one
+	if strings.Contains(m[2], "\n"+m[1][:3]) {
+		return content
+	}
+	return m[2]
+}
+
+// StripQuotes removes matching quotes or backticks surrounding the whole content
+func StripQuotes(content string) string {
+	trimmed := strings.TrimSpace(content)
+	for _, q := range [][2]string{{`"`, `"`}, {"'", "'"}, {"`", "`"}, {"“", "”"}, {"‘", "’"}} {
+		if len(trimmed) >= len(q[0])+len(q[1]) &&
+			strings.HasPrefix(trimmed, q[0]) && strings.HasSuffix(trimmed, q[1]) {
+			return trimmed[len(q[0]) : len(trimmed)-len(q[1])]
+		}
+	}
+	return content
+}
+
+// ExtractJSON returns the first JSON object or array found in content, e.g. inside a
+// code block or after an introduction sentence
+func ExtractJSON(content string) (string, error) {
+	for i := 0; i < len(content); i++ {
+		if content[i] != '{' && content[i] != '[' {
+			continue
+		}
+		var raw json.RawMessage
+		if err := json.NewDecoder(strings.NewReader(content[i:])).Decode(&raw); err == nil {
+			return string(raw), nil
+		}
+	}
+	return "", fmt.Errorf("no JSON object or array found")
+}
+
+// Capture returns the given group of the first match of pattern. Group 0 selects the
+// first capture group when the pattern has one, the whole match otherwise.
+func Capture(content, pattern string, group int) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %w", err)
+	}
+	if group == 0 && re.NumSubexp() > 0 {
+		group = 1
+	}
+	if group > re.NumSubexp() {
+		return "", fmt.Errorf("group %d out of range, pattern has %d", group, re.NumSubexp())
+	}
+
+	m := re.FindStringSubmatch(content)
+	if m == nil {
+		return "", fmt.Errorf("pattern %q does not match", pattern)
+	}
+	return m[group], nil
+}
+
+// Wrap breaks lines longer than width at spaces. Existing line breaks are kept and
+// continuation lines keep the indentation of the line they come from.
+func Wrap(content string, width int) string {
+	var buf bytes.Buffer
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		words := strings.Fields(line)
+		if len(line) <= width || len(words) < 2 {
+			buf.WriteString(line)
+			continue
+		}
+
+		n := len(indent)
+		buf.WriteString(indent)
+		for j, word := range words {
+			if j > 0 && n+1+len(word) > width {
+				buf.WriteString("\n" + indent)
+				n = len(indent)
+			} else if j > 0 {
+				buf.WriteByte(' ')
+				n++
+			}
+			buf.WriteString(word)
+			n += len(word)
+		}
+	}
+	return buf.String()
+}
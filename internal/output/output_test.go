@@ -0,0 +1,111 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/y0ug/ai-helper/internal/config"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		step    config.OutputStep
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"trim", config.OutputStep{Type: "trim"}, "  hello \n\n", "hello", false},
+		{
+			"strip fences",
+			config.OutputStep{Type: "strip_fences"},
+			"```text\nfeat: add flag\n\n- details\n```\n",
+			"feat: add flag\n\n- details",
+			false,
+		},
+		{
+			"strip fences keeps text around blocks",
+			config.OutputStep{Type: "strip_fences"},
+			"Here:\n```\ncode\n```",
+			"Here:\n```\ncode\n```",
+			false,
+		},
+		{
+			"strip fences keeps several blocks",
+			config.OutputStep{Type: "strip_fences"},
+			"```\na\n```\n```\nb\n```",
+			"```\na\n```\n```\nb\n```",
+			false,
+		},
+		{"strip quotes", config.OutputStep{Type: "strip_quotes"}, "\"fix: typo\"\n", "fix: typo", false},
+		{"strip curly quotes", config.OutputStep{Type: "strip_quotes"}, "“hi”", "hi", false},
+		{"strip quotes unbalanced", config.OutputStep{Type: "strip_quotes"}, "\"hi", "\"hi", false},
+		{
+			"extract json from block",
+			config.OutputStep{Type: "extract_json"},
+			"Sure! Here you go:\n```json\n{\"name\": \"bob\"}\n```",
+			"{\"name\": \"bob\"}",
+			false,
+		},
+		{
+			"extract json skips braces that are not json",
+			config.OutputStep{Type: "extract_json"},
+			"Use {name} as key: [1, 2]",
+			"[1, 2]",
+			false,
+		},
+		{"extract json missing", config.OutputStep{Type: "extract_json"}, "no json", "", true},
+		{"validate json", config.OutputStep{Type: "validate_json"}, "{\"a\": 1}\n", "{\"a\": 1}\n", false},
+		{"validate json invalid", config.OutputStep{Type: "validate_json"}, "{a: 1}", "", true},
+		{
+			"regex first group by default",
+			config.OutputStep{Type: "regex", Pattern: `version (\d+\.\d+)`},
+			"the version 1.23 is out",
+			"1.23",
+			false,
+		},
+		{
+			"regex whole match",
+			config.OutputStep{Type: "regex", Pattern: `\d+`},
+			"answer: 42",
+			"42",
+			false,
+		},
+		{"regex no match", config.OutputStep{Type: "regex", Pattern: `\d+`}, "none", "", true},
+		{
+			"wrap",
+			config.OutputStep{Type: "wrap", Width: 10},
+			"short\n  one two three four",
+			"short\n  one two\n  three\n  four",
+			false,
+		},
+		{"unknown", config.OutputStep{Type: "shout"}, "x", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(tt.input, tt.step)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcess(t *testing.T) {
+	steps := []config.OutputStep{{Type: "strip_fences"}, {Type: "strip_quotes"}, {Type: "trim"}}
+	got, err := Process("```\n\"chore: bump deps\"\n```", steps)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if got != "chore: bump deps" {
+		t.Errorf("Process() = %q, want %q", got, "chore: bump deps")
+	}
+
+	_, err = Process("text", []config.OutputStep{{Type: "trim"}, {Type: "validate_json"}})
+	if err == nil || err.Error() != "output step 2 (validate_json): response is not valid JSON" {
+		t.Errorf("Process() error = %v, want the failing step", err)
+	}
+}
@@ -0,0 +1,334 @@
+package patch
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Result is a change applied in memory, ready to be previewed and written
+type Result struct {
+	Change
+	Exists     bool
+	OldContent string
+	NewContent string
+}
+
+// Prepare reads the files targeted by the changes and applies the changes in memory.
+// Nothing is written; an error is returned if any hunk does not apply.
+func Prepare(changes []Change) ([]Result, error) {
+	// Later changes to the same file apply on top of earlier ones
+	pending := make(map[string]*Result)
+	var order []string
+
+	for _, change := range changes {
+		res, ok := pending[change.Path]
+		if !ok {
+			res = &Result{Change: change}
+			data, err := os.ReadFile(change.Path)
+			switch {
+			case err == nil:
+				res.Exists = true
+				res.OldContent = string(data)
+			case !os.IsNotExist(err):
+				return nil, fmt.Errorf("failed to read %s: %w", change.Path, err)
+			}
+			res.NewContent = res.OldContent
+			pending[change.Path] = res
+			order = append(order, change.Path)
+		}
+		res.Delete = change.Delete
+
+		switch {
+		case change.Content != nil:
+			res.NewContent = *change.Content
+		case change.Delete:
+			if !res.Exists {
+				return nil, fmt.Errorf("cannot delete %s: file does not exist", change.Path)
+			}
+			res.NewContent = ""
+		default:
+			if !res.Exists && !change.Create && !ok {
+				return nil, fmt.Errorf("cannot patch %s: file does not exist", change.Path)
+			}
+			updated, err := applyHunks(res.NewContent, change.Hunks)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", change.Path, err)
+			}
+			res.NewContent = updated
+		}
+	}
+
+	results := make([]Result, 0, len(order))
+	for _, path := range order {
+		results = append(results, *pending[path])
+	}
+	return results, nil
+}
+
+// applyHunks applies diff hunks to content. Each hunk is located by its context and
+// removed lines, starting near its declared position since generated line numbers
+// are often off.
+func applyHunks(content string, hunks []Hunk) (string, error) {
+	lines := splitLines(content)
+	offset := 0 // shift of line numbers caused by previous hunks
+	minPos := 0 // hunks apply in order and cannot overlap
+
+	for i, hunk := range hunks {
+		var oldLines, newLines []string
+		for _, line := range hunk.Lines {
+			if line[0] != '+' {
+				oldLines = append(oldLines, line[1:])
+			}
+			if line[0] != '-' {
+				newLines = append(newLines, line[1:])
+			}
+		}
+
+		// The lines of a pure insertion, -N,0, go after line N
+		start := hunk.OldStart - 1
+		if hunk.OldCount == 0 {
+			start = hunk.OldStart
+		}
+		expected := max(start+offset, minPos)
+		pos := findLines(lines, oldLines, expected, minPos)
+		if pos < 0 {
+			return "", fmt.Errorf("hunk %d (%s) does not apply", i+1, hunk.Header)
+		}
+
+		// Context lines keep the file's version in case they only matched loosely
+		kept := make([]string, 0, len(newLines))
+		old := pos
+		for _, line := range hunk.Lines {
+			switch line[0] {
+			case '+':
+				kept = append(kept, line[1:])
+			case '-':
+				old++
+			default:
+				kept = append(kept, lines[old])
+				old++
+			}
+		}
+
+		updated := make([]string, 0, len(lines)-len(oldLines)+len(kept))
+		updated = append(updated, lines[:pos]...)
+		updated = append(updated, kept...)
+		updated = append(updated, lines[pos+len(oldLines):]...)
+		lines = updated
+
+		offset += len(newLines) - len(oldLines)
+		minPos = pos + len(newLines)
+	}
+
+	if len(lines) == 0 {
+		return "", nil
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// findLines returns the position of block in lines closest to expected, at or after
+// minPos, or -1. Lines are first compared exactly, then ignoring surrounding whitespace.
+func findLines(lines, block []string, expected, minPos int) int {
+	if len(block) == 0 {
+		return min(expected, len(lines))
+	}
+
+	for _, equal := range []func(a, b string) bool{
+		func(a, b string) bool { return a == b },
+		func(a, b string) bool { return strings.TrimSpace(a) == strings.TrimSpace(b) },
+	} {
+		matches := func(pos int) bool {
+			if pos < minPos || pos+len(block) > len(lines) {
+				return false
+			}
+			for i, line := range block {
+				if !equal(lines[pos+i], line) {
+					return false
+				}
+			}
+			return true
+		}
+
+		for delta := 0; delta <= len(lines); delta++ {
+			if matches(expected - delta) {
+				return expected - delta
+			}
+			if matches(expected + delta) {
+				return expected + delta
+			}
+		}
+	}
+	return -1
+}
+
+// Preview writes a unified diff of every result, colored when color is true
+func Preview(w io.Writer, results []Result, color bool) {
+	for _, res := range results {
+		oldPath, newPath := res.Path, res.Path
+		if !res.Exists {
+			oldPath = ""
+		}
+		if res.Delete {
+			newPath = ""
+		}
+
+		diff := UnifiedDiff(oldPath, newPath, res.OldContent, res.NewContent)
+		if diff == "" {
+			fmt.Fprintf(w, "%s: no changes\n", res.Path)
+			continue
+		}
+
+		for _, line := range strings.SplitAfter(diff, "\n") {
+			if !color || line == "" {
+				fmt.Fprint(w, line)
+				continue
+			}
+			switch {
+			case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+				fmt.Fprint(w, "\x1b[1m"+strings.TrimSuffix(line, "\n")+"\x1b[0m\n")
+			case strings.HasPrefix(line, "@@"):
+				fmt.Fprint(w, "\x1b[36m"+strings.TrimSuffix(line, "\n")+"\x1b[0m\n")
+			case strings.HasPrefix(line, "+"):
+				fmt.Fprint(w, "\x1b[32m"+strings.TrimSuffix(line, "\n")+"\x1b[0m\n")
+			case strings.HasPrefix(line, "-"):
+				fmt.Fprint(w, "\x1b[31m"+strings.TrimSuffix(line, "\n")+"\x1b[0m\n")
+			default:
+				fmt.Fprint(w, line)
+			}
+		}
+	}
+}
+
+// Write writes the results to disk. Every new content is first written to a temporary
+// file next to its target, so a failure leaves the files as they were; the temporary
+// files then replace the targets and deleted files are removed.
+func Write(results []Result) error {
+	staged := make(map[string]string) // target path -> temporary file
+	defer func() {
+		for _, tmp := range staged {
+			os.Remove(tmp)
+		}
+	}()
+
+	for _, res := range results {
+		if res.Delete {
+			if _, err := os.Stat(res.Path); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", res.Path, err)
+			}
+			continue
+		}
+		tmp, err := stage(res)
+		if err != nil {
+			return err
+		}
+		staged[res.Path] = tmp
+	}
+
+	for _, res := range results {
+		if res.Delete {
+			if err := os.Remove(res.Path); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", res.Path, err)
+			}
+			continue
+		}
+		if err := os.Rename(staged[res.Path], res.Path); err != nil {
+			return fmt.Errorf("failed to write %s: %w", res.Path, err)
+		}
+		delete(staged, res.Path)
+	}
+	return nil
+}
+
+// stage writes the new content of a result to a temporary file in the target's
+// directory, with the target's permissions, and returns its path
+func stage(res Result) (string, error) {
+	dir := filepath.Dir(res.Path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(res.Path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(res.Path)+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", res.Path, err)
+	}
+	_, err = f.WriteString(res.NewContent)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), mode)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write %s: %w", res.Path, err)
+	}
+	return f.Name(), nil
+}
+
+// Backup saves the current state of the files about to change. Inside a git work tree
+// where every existing file is tracked, uncommitted changes are recorded as a stash
+// entry without touching the working tree. Otherwise files are copied under backupDir.
+// It returns a message telling how to restore.
+func Backup(results []Result, backupDir string) (string, error) {
+	if gitTracked(results) {
+		out, err := exec.Command("git", "stash", "create").Output()
+		if err != nil {
+			return "", fmt.Errorf("git stash create failed: %w", err)
+		}
+		ref := strings.TrimSpace(string(out))
+		if ref == "" {
+			return "Working tree was clean, restore with: git checkout -- <files>", nil
+		}
+		msg := "ai-helper: before applying patch"
+		if err := exec.Command("git", "stash", "store", "-m", msg, ref).Run(); err != nil {
+			return "", fmt.Errorf("git stash store failed: %w", err)
+		}
+		return fmt.Sprintf("Previous state saved as git stash %s, restore with: git stash apply", ref[:8]), nil
+	}
+
+	dir := filepath.Join(backupDir, time.Now().Format("20060102-150405"))
+	saved := 0
+	for _, res := range results {
+		if !res.Exists {
+			continue
+		}
+		dest := filepath.Join(dir, res.Path)
+		if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+			return "", fmt.Errorf("failed to create backup directory: %w", err)
+		}
+		if err := os.WriteFile(dest, []byte(res.OldContent), 0600); err != nil {
+			return "", fmt.Errorf("failed to back up %s: %w", res.Path, err)
+		}
+		saved++
+	}
+	if saved == 0 {
+		return "Only new files are created, nothing to back up", nil
+	}
+	return fmt.Sprintf("Previous files backed up to %s", dir), nil
+}
+
+// gitTracked reports whether every existing file of results is tracked by git
+func gitTracked(results []Result) bool {
+	if err := exec.Command("git", "rev-parse", "--is-inside-work-tree").Run(); err != nil {
+		return false
+	}
+	for _, res := range results {
+		if !res.Exists {
+			continue
+		}
+		if err := exec.Command("git", "ls-files", "--error-unmatch", res.Path).Run(); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,155 @@
+package patch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyHunks(t *testing.T) {
+	content := "one\ntwo\nthree\nfour\nfive\nsix\n"
+
+	tests := []struct {
+		name    string
+		hunks   []Hunk
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "exact position",
+			hunks: []Hunk{{OldStart: 2, OldCount: 3, Lines: []string{" two", "-three", "+THREE", " four"}}},
+			want:  "one\ntwo\nTHREE\nfour\nfive\nsix\n",
+		},
+		{
+			name:  "wrong line number",
+			hunks: []Hunk{{OldStart: 1, OldCount: 2, Lines: []string{" five", "-six", "+seven"}}},
+			want:  "one\ntwo\nthree\nfour\nfive\nseven\n",
+		},
+		{
+			name:  "whitespace differences",
+			hunks: []Hunk{{OldStart: 1, OldCount: 1, Lines: []string{"  one ", "+one and a half"}}},
+			want:  "one\none and a half\ntwo\nthree\nfour\nfive\nsix\n",
+		},
+		{
+			name: "several hunks",
+			hunks: []Hunk{
+				{OldStart: 1, OldCount: 1, Lines: []string{"-one", "+1"}},
+				{OldStart: 6, OldCount: 1, Lines: []string{"-six", "+6"}},
+			},
+			want: "1\ntwo\nthree\nfour\nfive\n6\n",
+		},
+		{
+			name:  "pure insertion",
+			hunks: []Hunk{{OldStart: 2, OldCount: 0, Lines: []string{"+two and a half"}}},
+			want:  "one\ntwo\ntwo and a half\nthree\nfour\nfive\nsix\n",
+		},
+		{
+			name:  "insertion at the top",
+			hunks: []Hunk{{OldStart: 0, OldCount: 0, Lines: []string{"+zero"}}},
+			want:  "zero\none\ntwo\nthree\nfour\nfive\nsix\n",
+		},
+		{
+			name: "insertion after a change",
+			hunks: []Hunk{
+				{OldStart: 1, OldCount: 1, Lines: []string{"-one", "+1", "+1.5"}},
+				{OldStart: 4, OldCount: 0, Lines: []string{"+four and a half"}},
+			},
+			want: "1\n1.5\ntwo\nthree\nfour\nfour and a half\nfive\nsix\n",
+		},
+		{
+			name:    "context not found",
+			hunks:   []Hunk{{Header: "@@ -3 +3 @@", OldStart: 3, OldCount: 1, Lines: []string{"-missing", "+x"}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyHunks(content, tt.hunks)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyHunks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("applyHunks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareAndWrite(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := os.WriteFile("main.go", []byte("package main\n\nvar x = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := Parse("```diff\n--- a/main.go\n+++ b/main.go\n@@ -3 +3 @@\n-var x = 1\n+var x = 2\n```\n\n" +
+		"```text sub/notes.txt\nhello\n```\n")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	results, err := Prepare(changes)
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	var preview strings.Builder
+	Preview(&preview, results, false)
+	for _, want := range []string{"-var x = 1", "+var x = 2", "--- /dev/null", "+hello"} {
+		if !strings.Contains(preview.String(), want) {
+			t.Errorf("Preview() missing %q in:\n%s", want, preview.String())
+		}
+	}
+
+	msg, err := Backup(results, filepath.Join(dir, "backups"))
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	if !strings.Contains(msg, "backups") {
+		t.Errorf("Backup() = %q, want a copy outside git", msg)
+	}
+
+	if err := Write(results); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	for path, want := range map[string]string{
+		"main.go":       "package main\n\nvar x = 2\n",
+		"sub/notes.txt": "hello\n",
+	} {
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q (%v), want %q", path, data, err, want)
+		}
+	}
+
+	// A file that cannot be written leaves the others untouched
+	failing := []Result{
+		{Change: Change{Path: "main.go"}, Exists: true, NewContent: "package main\n\nvar x = 3\n"},
+		{Change: Change{Path: "main.go/child.txt"}, NewContent: "x\n"},
+	}
+	if err := Write(failing); err == nil {
+		t.Error("Write() into a path under a file should return an error")
+	}
+	if data, _ := os.ReadFile("main.go"); string(data) != "package main\n\nvar x = 2\n" {
+		t.Errorf("main.go = %q after a failed Write, want it unchanged", data)
+	}
+	if tmp, _ := filepath.Glob(".main.go.*"); len(tmp) > 0 {
+		t.Errorf("temporary files left: %v", tmp)
+	}
+
+	// A hunk that does not apply leaves everything untouched
+	bad, _ := ParseDiff("--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-package other\n+package x\n")
+	if _, err := Prepare(bad); err == nil {
+		t.Error("Prepare() with a failing hunk should return an error")
+	}
+}
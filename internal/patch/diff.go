@@ -0,0 +1,161 @@
+package patch
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	diffContext = 3
+	// maxDiffCells bounds the size of the LCS table, larger inputs are shown as a full rewrite
+	maxDiffCells = 4_000_000
+)
+
+// splitLines splits text into lines without their line terminators
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// lineDiff returns the edit script turning a into b, one line per entry prefixed
+// with ' ' (kept), '-' (removed) or '+' (added)
+func lineDiff(a, b []string) []string {
+	if len(a)*len(b) > maxDiffCells {
+		var script []string
+		for _, line := range a {
+			script = append(script, "-"+line)
+		}
+		for _, line := range b {
+			script = append(script, "+"+line)
+		}
+		return script
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var script []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			script = append(script, " "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			script = append(script, "-"+a[i])
+			i++
+		default:
+			script = append(script, "+"+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		script = append(script, "-"+a[i])
+	}
+	for ; j < len(b); j++ {
+		script = append(script, "+"+b[j])
+	}
+	return script
+}
+
+// UnifiedDiff returns a unified diff between two versions of a file, or an empty
+// string when they are identical. Empty oldPath or newPath stand for /dev/null.
+func UnifiedDiff(oldPath, newPath, oldText, newText string) string {
+	script := lineDiff(splitLines(oldText), splitLines(newText))
+
+	changed := false
+	for _, line := range script {
+		if line[0] != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", diffPath("a/", oldPath), diffPath("b/", newPath))
+
+	// Group changes into hunks with surrounding context
+	for start := 0; start < len(script); {
+		if script[start][0] == ' ' {
+			start++
+			continue
+		}
+
+		from := max(start-diffContext, 0)
+		end := start
+		for end < len(script) {
+			if script[end][0] != ' ' {
+				end++
+				continue
+			}
+			// Stop once the unchanged run is long enough to separate hunks
+			run := end
+			for run < len(script) && script[run][0] == ' ' {
+				run++
+			}
+			if run == len(script) || run-end > 2*diffContext {
+				end = min(end+diffContext, len(script))
+				break
+			}
+			end = run
+		}
+
+		oldStart, newStart := 1, 1
+		for _, line := range script[:from] {
+			if line[0] != '+' {
+				oldStart++
+			}
+			if line[0] != '-' {
+				newStart++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, line := range script[from:end] {
+			if line[0] != '+' {
+				oldCount++
+			}
+			if line[0] != '-' {
+				newCount++
+			}
+		}
+		if oldCount == 0 {
+			oldStart--
+		}
+		if newCount == 0 {
+			newStart--
+		}
+
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, line := range script[from:end] {
+			sb.WriteString(line + "\n")
+		}
+		start = end
+	}
+
+	return sb.String()
+}
+
+func diffPath(prefix, path string) string {
+	if path == "" {
+		return "/dev/null"
+	}
+	return prefix + path
+}
@@ -0,0 +1,209 @@
+package patch
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/y0ug/ai-helper/internal/markdown"
+)
+
+// Change is a modification of one file, either a set of diff hunks or its whole content
+type Change struct {
+	Path    string  // file to modify, relative to the working directory
+	Hunks   []Hunk  // set for unified diffs
+	Content *string // set for whole-file blocks
+	Create  bool    // the diff creates the file
+	Delete  bool    // the diff deletes the file
+}
+
+// Hunk is a block of changes of a unified diff
+type Hunk struct {
+	Header   string
+	OldStart int
+	OldCount int      // 0 for a pure insertion after line OldStart
+	Lines    []string // prefixed with ' ', '-' or '+'
+}
+
+var (
+	hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+\d+(?:,\d+)? @@`)
+	captionRe    = regexp.MustCompile("^(?i:(?:file|path|filename)\\s*:\\s*)?[*_`\"']*([^\\s*`\"']+?)[*_`\"']*:?$")
+	infoPathRe   = regexp.MustCompile(`(?:^|\s)(?:title|file|path|filename)=["']?([^"'\s]+)`)
+)
+
+// Parse finds the file changes proposed in a response: unified diffs, fenced or not,
+// and fenced blocks holding a whole file whose path is given in the fence info string
+// (e.g. ```go main.go) or on the line before the block (e.g. "File: main.go").
+func Parse(content string) ([]Change, error) {
+	var changes []Change
+	blocks := markdown.ExtractCodeBlocks(content)
+
+	for _, block := range blocks {
+		if isDiff(block.Lang, block.Code) {
+			diffChanges, err := ParseDiff(block.Code)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, diffChanges...)
+			continue
+		}
+
+		path := blockPath(block)
+		if path == "" {
+			continue
+		}
+		code := block.Code + "\n"
+		changes = append(changes, Change{Path: path, Content: &code})
+	}
+
+	// The whole response may be a bare diff
+	if len(blocks) == 0 && isDiff("", content) {
+		return ParseDiff(content)
+	}
+
+	for _, c := range changes {
+		if err := checkPath(c.Path); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+func isDiff(lang, code string) bool {
+	if lang == "diff" || lang == "patch" {
+		return true
+	}
+	trimmed := strings.TrimSpace(code)
+	return strings.HasPrefix(trimmed, "--- ") || strings.HasPrefix(trimmed, "diff --git ")
+}
+
+// blockPath returns the file path declared for a code block, if any
+func blockPath(block markdown.CodeBlock) string {
+	if m := infoPathRe.FindStringSubmatch(block.Info); m != nil {
+		return m[1]
+	}
+	if fields := strings.Fields(block.Info); len(fields) > 0 && looksLikePath(fields[0]) {
+		return fields[0]
+	}
+	// Captions are often decorated, e.g. "### `main.go`" or "**File: main.go**"
+	caption := strings.Trim(block.Caption, "#*_ ")
+	if m := captionRe.FindStringSubmatch(caption); m != nil && looksLikePath(m[1]) {
+		return m[1]
+	}
+	return ""
+}
+
+func looksLikePath(s string) bool {
+	return !strings.Contains(s, "=") &&
+		(strings.Contains(s, "/") || filepath.Ext(s) != "") &&
+		!strings.HasSuffix(s, ".")
+}
+
+// checkPath rejects paths that would write outside the working directory
+func checkPath(path string) error {
+	if filepath.IsAbs(path) {
+		return fmt.Errorf("refusing to modify absolute path %s", path)
+	}
+	clean := filepath.Clean(path)
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("refusing to modify path outside the working directory: %s", path)
+	}
+	return nil
+}
+
+// ParseDiff parses a unified diff, as produced by diff -u or git diff, into changes.
+// Hunk line counts are ignored since generated diffs often get them wrong.
+func ParseDiff(diff string) ([]Change, error) {
+	var changes []Change
+	var current *Change
+	var hunk *Hunk
+
+	lines := strings.Split(strings.ReplaceAll(diff, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			oldPath := parseDiffPath(line[4:])
+			newPath := parseDiffPath(lines[i+1][4:])
+			i++
+
+			changes = append(changes, Change{})
+			current = &changes[len(changes)-1]
+			hunk = nil
+			switch {
+			case oldPath == "" && newPath == "":
+				return nil, fmt.Errorf("diff without file path")
+			case oldPath == "":
+				current.Path, current.Create = newPath, true
+			case newPath == "":
+				current.Path, current.Delete = oldPath, true
+			default:
+				current.Path = newPath
+			}
+			continue
+		}
+
+		if m := hunkHeaderRe.FindStringSubmatch(line); m != nil {
+			if current == nil {
+				return nil, fmt.Errorf("hunk %q before any file header", line)
+			}
+			start, _ := strconv.Atoi(m[1])
+			count := 1
+			if m[2] != "" {
+				count, _ = strconv.Atoi(m[2])
+			}
+			current.Hunks = append(current.Hunks, Hunk{Header: m[0], OldStart: start, OldCount: count})
+			hunk = &current.Hunks[len(current.Hunks)-1]
+			continue
+		}
+
+		if hunk == nil {
+			// diff --git, index and other extended headers
+			continue
+		}
+
+		switch {
+		case line == "":
+			// Blank context lines often lose their leading space
+			hunk.Lines = append(hunk.Lines, " ")
+		case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+			hunk.Lines = append(hunk.Lines, line)
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file"
+		default:
+			hunk = nil
+		}
+	}
+
+	// Drop blank context lines picked up after the last real line of each hunk
+	for i := range changes {
+		for j := range changes[i].Hunks {
+			h := &changes[i].Hunks[j]
+			for len(h.Lines) > 0 && h.Lines[len(h.Lines)-1] == " " {
+				h.Lines = h.Lines[:len(h.Lines)-1]
+			}
+		}
+		if err := checkPath(changes[i].Path); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("no file found in diff")
+	}
+	return changes, nil
+}
+
+// parseDiffPath extracts the path from a ---/+++ header, returning "" for /dev/null
+func parseDiffPath(header string) string {
+	path := strings.TrimSpace(strings.SplitN(header, "\t", 2)[0])
+	if path == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(path, "a/") || strings.HasPrefix(path, "b/") {
+		path = path[2:]
+	}
+	return path
+}
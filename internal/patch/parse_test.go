@@ -0,0 +1,104 @@
+package patch
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Change
+		wantErr bool
+	}{
+		{
+			name: "fenced diff",
+			content: "Fix:\n\n```diff\n--- a/main.go\n+++ b/main.go\n@@ -1,2 +1,2 @@\n" +
+				" package main\n-var x = 1\n+var x = 2\n```\n",
+			want: []Change{{Path: "main.go", Hunks: []Hunk{{
+				Header:   "@@ -1,2 +1,2 @@",
+				OldStart: 1,
+				OldCount: 2,
+				Lines:    []string{" package main", "-var x = 1", "+var x = 2"},
+			}}}},
+		},
+		{
+			name:    "bare diff creating a file",
+			content: "--- /dev/null\n+++ b/docs/new.md\n@@ -0,0 +1 @@\n+# New\n",
+			want: []Change{{Path: "docs/new.md", Create: true, Hunks: []Hunk{{
+				Header: "@@ -0,0 +1 @@", Lines: []string{"+# New"},
+			}}}},
+		},
+		{
+			name:    "path in info string",
+			content: "```go cmd/main.go\npackage main\n```\n",
+			want:    []Change{{Path: "cmd/main.go", Content: strPtr("package main\n")}},
+		},
+		{
+			name:    "path in caption",
+			content: "**File: `config.yaml`**\n\n```yaml\nkey: value\n```\n",
+			want:    []Change{{Path: "config.yaml", Content: strPtr("key: value\n")}},
+		},
+		{
+			name:    "block without path is ignored",
+			content: "Run this:\n\n```bash\nls -la\n```\n",
+			want:    nil,
+		},
+		{
+			name:    "path outside working directory",
+			content: "```sh ../evil.sh\nrm -rf /\n```\n",
+			wantErr: true,
+		},
+		{
+			name:    "absolute path in diff",
+			content: "--- /etc/passwd\n+++ /etc/passwd\n@@ -1 +1 @@\n-a\n+b\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Parse() returned %d changes, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range tt.want {
+				checkChange(t, got[i], tt.want[i])
+			}
+		})
+	}
+}
+
+func checkChange(t *testing.T, got, want Change) {
+	t.Helper()
+	if got.Path != want.Path || got.Create != want.Create || got.Delete != want.Delete {
+		t.Errorf("change = %+v, want %+v", got, want)
+	}
+	if (got.Content == nil) != (want.Content == nil) ||
+		(got.Content != nil && *got.Content != *want.Content) {
+		t.Errorf("change %s content mismatch", got.Path)
+	}
+	if len(got.Hunks) != len(want.Hunks) {
+		t.Fatalf("change %s has %d hunks, want %d", got.Path, len(got.Hunks), len(want.Hunks))
+	}
+	for j, h := range want.Hunks {
+		g := got.Hunks[j]
+		if g.Header != h.Header || g.OldStart != h.OldStart || g.OldCount != h.OldCount || len(g.Lines) != len(h.Lines) {
+			t.Errorf("hunk %d = %+v, want %+v", j, g, h)
+			continue
+		}
+		for k := range h.Lines {
+			if g.Lines[k] != h.Lines[k] {
+				t.Errorf("hunk %d line %d = %q, want %q", j, k, g.Lines[k], h.Lines[k])
+			}
+		}
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
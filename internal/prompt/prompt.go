@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"text/template"
@@ -27,12 +28,20 @@ func NewTemplateData(input string) *TemplateData {
 	}
 }
 
-// LoadEnvironment loads all environment variables into the template data
-func (td *TemplateData) LoadEnvironment() {
+// LoadEnvironment replaces the environment variables of the template data with those
+// whose name matches one of the patterns, e.g. "HOME" or "GIT_*"
+func (td *TemplateData) LoadEnvironment(patterns []string) {
+	td.Env = make(map[string]string)
 	for _, env := range os.Environ() {
 		pair := strings.SplitN(env, "=", 2)
-		if len(pair) == 2 {
-			td.Env[pair[0]] = pair[1]
+		if len(pair) != 2 {
+			continue
+		}
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, pair[0]); ok {
+				td.Env[pair[0]] = pair[1]
+				break
+			}
 		}
 	}
 }
@@ -68,25 +77,101 @@ func GetTemplateFuncs(td *TemplateData) template.FuncMap {
 			if !ok {
 				return fmt.Sprintf("Error: file %s not found", path)
 			}
-			ext := filepath.Ext(path)
-			return fmt.Sprintf("```%s\n%s\n```", ext[1:], content)
+			return FormatFile(path, content)
 		},
 	}
 }
 
-// Execute processes a template with the provided template data
-func Execute(templateContent string, data *TemplateData) (string, error) {
-	tmpl, err := template.New("prompt").
-		Funcs(GetTemplateFuncs(data)).
-		Parse(templateContent)
+// FormatFile returns file content as a fenced code block tagged with the file extension
+func FormatFile(path, content string) string {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	return fmt.Sprintf("```%s\n%s\n```", ext, content)
+}
+
+// ExpandFiles expands glob patterns into the list of matching files
+func ExpandFiles(patterns []string) ([]string, error) {
+	var paths []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no file matches %s", pattern)
+		}
+		found := len(paths)
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, fmt.Errorf("error reading file %s: %w", match, err)
+			}
+			if !info.IsDir() {
+				paths = append(paths, match)
+			}
+		}
+		if len(paths) == found {
+			return nil, fmt.Errorf("%s only matches directories", pattern)
+		}
+	}
+	return paths, nil
+}
+
+// Parse parses a template along with the define blocks of partials, with the helper
+// functions available to templates
+func Parse(templateContent, partials string) (*template.Template, error) {
+	tmpl := template.New("prompt").Funcs(GetTemplateFuncs(NewTemplateData("")))
+	if partials != "" {
+		if _, err := tmpl.New("partials").Parse(partials); err != nil {
+			return nil, fmt.Errorf("error parsing partials: %w", err)
+		}
+	}
+	if _, err := tmpl.Parse(templateContent); err != nil {
+		return nil, fmt.Errorf("error parsing template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Options changes how Render renders a template
+type Options struct {
+	Partials string // define blocks the template can use, e.g. {{ template "rules" . }}
+	Strict   bool   // fail on a missing key instead of rendering "<no value>"
+}
+
+// Render renders a prompt template, the one way command templates are rendered. Besides
+// .Input, .Env, .Files and .Vars, the variables are available at the top level, e.g.
+// .RecentCommits for .Vars.RecentCommits. The template may redefine a partial.
+func Render(templateContent string, data *TemplateData, opts Options) (string, error) {
+	tmpl, err := Parse(templateContent, opts.Partials)
 	if err != nil {
-		return "", fmt.Errorf("error parsing template: %w", err)
+		return "", err
+	}
+	tmpl.Funcs(GetTemplateFuncs(data))
+	if opts.Strict {
+		tmpl.Option("missingkey=error")
 	}
 
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
+	if err := tmpl.ExecuteTemplate(&buf, "prompt", data.fields()); err != nil {
 		return "", fmt.Errorf("error executing template: %w", err)
 	}
 
 	return buf.String(), nil
 }
+
+// BuiltinFields are the fields of TemplateData, names no variable can take at the top
+// level of templates
+var BuiltinFields = []string{"Input", "Env", "Files", "Vars"}
+
+// fields returns the data as seen by templates, the built-in fields taking precedence
+// over variables of the same name
+func (td *TemplateData) fields() map[string]interface{} {
+	fields := make(map[string]interface{}, len(td.Vars)+4)
+	for name, value := range td.Vars {
+		fields[name] = value
+	}
+	fields["Input"] = td.Input
+	fields["Env"] = td.Env
+	fields["Files"] = td.Files
+	fields["Vars"] = td.Vars
+	return fields
+}
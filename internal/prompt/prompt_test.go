@@ -0,0 +1,48 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	data := NewTemplateData("the input")
+	data.Env["USER"] = "alice"
+	data.Files["main.go"] = "package main"
+	data.Vars["Lang"] = "go"
+	data.Vars["Input"] = "shadowed"
+
+	tests := []struct {
+		name    string
+		content string
+		opts    Options
+		want    string
+		wantErr string
+	}{
+		{"built-in fields", "{{ .Input }} {{ .Env.USER }} {{ .Vars.Lang }}", Options{}, "the input alice go", ""},
+		{"variable at the top level", "{{ .Lang }}", Options{}, "go", ""},
+		{"functions", `{{ formatFile "main.go" }}`, Options{}, "```go\npackage main\n```", ""},
+		{"partials", `{{ template "lang" . }}!`, Options{Partials: `{{ define "lang" }}in {{ .Lang }}{{ end }}`}, "in go!", ""},
+		{"missing key", "{{ .Vars.Tone }}", Options{}, "<no value>", ""},
+		{"strict missing key", "{{ .Vars.Tone }}", Options{Strict: true}, "", `map has no entry for key "Tone"`},
+		{"strict missing variable", "{{ .Tone }}", Options{Strict: true}, "", `map has no entry for key "Tone"`},
+		{"strict missing env", "{{ .Env.HOME }}", Options{Strict: true}, "", `map has no entry for key "HOME"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.content, data, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Render() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+// Package secret finds credentials in text so they can be blocked or redacted before
+// leaving the machine
+package secret
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Policy tells what to do with secrets found in a request
+type Policy string
+
+const (
+	PolicyRedact Policy = "redact" // replace secrets with placeholders, the default
+	PolicyBlock  Policy = "block"  // refuse to send the request
+	PolicyWarn   Policy = "warn"   // send as is, only report the findings
+	PolicyOff    Policy = "off"    // do not scan
+)
+
+// ParsePolicy validates a policy name, an empty name giving the default
+func ParsePolicy(name string) (Policy, error) {
+	switch p := Policy(strings.ToLower(strings.TrimSpace(name))); p {
+	case "":
+		return PolicyRedact, nil
+	case PolicyRedact, PolicyBlock, PolicyWarn, PolicyOff:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown secret policy %q (valid: redact, block, warn, off)", name)
+	}
+}
+
+// Rule detects one kind of secret. When the pattern has capture groups, the secret is
+// the first group that matched, otherwise the whole match.
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// assignmentName matches variable names that usually hold credentials
+const assignmentName = `(?:[A-Za-z0-9_]*(?:SECRET|TOKEN|PASSWORD|PASSWD|API_?KEY|ACCESS_?KEY|PRIVATE_?KEY|CREDENTIALS?)[A-Za-z0-9_]*)`
+
+// Rules are the built-in detection rules
+var Rules = []Rule{
+	{"private-key", regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----(?s:.*?)(?:-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----|\z)`)},
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{60,})\b`)},
+	{"anthropic-key", regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`)},
+	{"openai-key", regexp.MustCompile(`\bsk-(?:proj-|svcacct-)?[A-Za-z0-9_-]{20,}`)},
+	{"google-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abeoprs]-[A-Za-z0-9-]{10,}`)},
+	{"stripe-key", regexp.MustCompile(`\b[rs]k_live_[A-Za-z0-9]{20,}\b`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{"url-password", regexp.MustCompile(`\b[a-z][a-z0-9+.-]*://[^\s:/@]+:([^\s@/]{3,})@`)},
+	{"assignment", regexp.MustCompile(`(?mi)^[+\-\s]*(?:export\s+)?"?` + assignmentName +
+		`"?\s*(?::=|[:=])\s*(?:"([^"\s]{8,})"|'([^'\s]{8,})'|([^\s'"#(){}\[\],;]{8,})\s*$)`)},
+}
+
+// Finding is a secret found in a text
+type Finding struct {
+	Rule  string
+	Value string
+	Start int // byte offsets of Value in the text
+	End   int
+}
+
+// String describes the finding without revealing the secret
+func (f Finding) String() string {
+	return fmt.Sprintf("%s (%s)", f.Rule, Mask(f.Value))
+}
+
+// Mask hides a secret, keeping a few leading characters to help identify it
+func Mask(value string) string {
+	if len(value) < 12 {
+		return "********"
+	}
+	return value[:4] + "********"
+}
+
+// Scan returns the secrets found in text, ordered by position and without overlaps
+func Scan(text string) []Finding {
+	return ScanWith(text, nil)
+}
+
+// ScanWith is Scan with extra rules checked after the built-in ones
+func ScanWith(text string, extra []Rule) []Finding {
+	var findings []Finding
+	for _, rule := range append(Rules[:len(Rules):len(Rules)], extra...) {
+		for _, m := range rule.Pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := m[0], m[1]
+			for g := 2; g+1 < len(m); g += 2 {
+				if m[g] >= 0 && m[g+1] > m[g] {
+					start, end = m[g], m[g+1]
+					break
+				}
+			}
+			if isPlaceholder(text[start:end]) {
+				continue
+			}
+			findings = append(findings, Finding{Rule: rule.Name, Value: text[start:end], Start: start, End: end})
+		}
+	}
+	findings = append(findings, scanEntropy(text)...)
+
+	// Keep the first of overlapping findings, rules being ordered by specificity
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Start < findings[j].Start })
+	var kept []Finding
+	for _, f := range findings {
+		if len(kept) > 0 && f.Start < kept[len(kept)-1].End {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+var (
+	tokenRe = regexp.MustCompile(`[A-Za-z0-9+/_=-]{32,}`)
+	// Values that look like secrets but are not: checksums of go.sum, npm integrity, etc.
+	checksumRe = regexp.MustCompile(`(?:h1:|sha\d+[-:]|sha\d+=)$`)
+)
+
+// scanEntropy finds long random-looking tokens that no specific rule matched
+func scanEntropy(text string) []Finding {
+	var findings []Finding
+	for _, loc := range tokenRe.FindAllStringIndex(text, -1) {
+		token := strings.TrimRight(text[loc[0]:loc[1]], "=")
+		prefix := text[max(loc[0]-8, 0):loc[0]]
+		if checksumRe.MatchString(prefix) || !mixedClasses(token) || wordy(token) || entropy(token) < 4 {
+			continue
+		}
+		findings = append(findings, Finding{
+			Rule:  "high-entropy",
+			Value: token,
+			Start: loc[0],
+			End:   loc[0] + len(token),
+		})
+	}
+	return findings
+}
+
+// mixedClasses reports whether s has lower case letters, upper case letters and digits,
+// which excludes identifiers, words and hex digests
+func mixedClasses(s string) bool {
+	var lower, upper, digit bool
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		}
+	}
+	return lower && upper && digit
+}
+
+// wordy reports whether most of s is made of runs of lower case letters, as in
+// identifiers and paths, while random tokens rarely have more than a few in a row
+func wordy(s string) bool {
+	inWords, run := 0, 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) && s[i] >= 'a' && s[i] <= 'z' {
+			run++
+			continue
+		}
+		if run >= 4 {
+			inWords += run
+		}
+		run = 0
+	}
+	return inWords*10 >= len(s)*4
+}
+
+// entropy returns the Shannon entropy of s in bits per character
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	var h float64
+	n := float64(len(s))
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+var placeholderRe = regexp.MustCompile(`^\[REDACTED_[A-Z0-9_]+\]$|^\*+$|^\$\{?[A-Za-z_][A-Za-z0-9_]*\}?$|^<[^>]+>$`)
+
+// isPlaceholder reports whether a value is a redaction, mask or reference rather than a secret
+func isPlaceholder(value string) bool {
+	return placeholderRe.MatchString(value) ||
+		strings.Contains(strings.ToLower(value), "your") ||
+		strings.Contains(value, "xxxx") || strings.Contains(value, "XXXX")
+}
+
+// Redactor replaces secrets with numbered placeholders such as [REDACTED_OPENAI_KEY_1].
+// The same secret always gets the same placeholder, so the model can still tell
+// whether two values are equal.
+type Redactor struct {
+	placeholders map[string]string
+	counts       map[string]int
+}
+
+// NewRedactor creates an empty Redactor
+func NewRedactor() *Redactor {
+	return &Redactor{placeholders: make(map[string]string), counts: make(map[string]int)}
+}
+
+// Redact replaces the findings of text, as returned by Scan, with placeholders
+func (r *Redactor) Redact(text string, findings []Finding) string {
+	var sb strings.Builder
+	last := 0
+	for _, f := range findings {
+		sb.WriteString(text[last:f.Start])
+		sb.WriteString(r.placeholder(f))
+		last = f.End
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+func (r *Redactor) placeholder(f Finding) string {
+	if p, ok := r.placeholders[f.Value]; ok {
+		return p
+	}
+	name := strings.ToUpper(strings.ReplaceAll(f.Rule, "-", "_"))
+	r.counts[name]++
+	p := fmt.Sprintf("[REDACTED_%s_%d]", name, r.counts[name])
+	r.placeholders[f.Value] = p
+	return p
+}
+
+// RedactString scans text, with the built-in and extra rules, and redacts every
+// secret found
+func RedactString(text string, extra []Rule) string {
+	return NewRedactor().Redact(text, ScanWith(text, extra))
+}
+
+// sensitiveNameRe matches environment variable names that hold credentials
+var sensitiveNameRe = regexp.MustCompile(`(?i)^` + assignmentName + `$`)
+
+// IsSensitiveName reports whether a variable name suggests it holds a credential,
+// e.g. OPENAI_API_KEY, GITHUB_TOKEN or DB_PASSWORD
+func IsSensitiveName(name string) bool {
+	return sensitiveNameRe.MatchString(name)
+}
+
+// BlockedError is returned when a request is refused because it contains secrets
+type BlockedError struct {
+	Findings []Finding
+}
+
+func (e *BlockedError) Error() string {
+	names := make([]string, len(e.Findings))
+	for i, f := range e.Findings {
+		names[i] = f.String()
+	}
+	return fmt.Sprintf("request blocked, possible secrets found: %s", strings.Join(names, ", "))
+}